@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	kitContext "github.com/exluap/kit/context"
+)
+
+// Rule specifies roles required to access a resource
+type Rule struct {
+	// Resource - gRPC full method (e.g. /pkg.Service/Method) or HTTP route (e.g. GET /api/users/{id})
+	Resource string
+	// AllOf - caller must have all the roles
+	AllOf []string `config:"all-of"`
+	// AnyOf - caller must have at least one of the roles
+	AnyOf []string `config:"any-of"`
+}
+
+// PolicyConfig is a table of access rules
+// it's supposed to be loaded as a part of service config by config.Loader
+type PolicyConfig struct {
+	Rules []*Rule
+	// DenyUnlisted - if true, access to resources which aren't listed is denied
+	DenyUnlisted bool `config:"deny-unlisted"`
+}
+
+// Policy checks access to resources according to the rules
+type Policy struct {
+	rules        map[string]*Rule
+	denyUnlisted bool
+}
+
+// NewPolicy creates a policy from the config
+func NewPolicy(cfg *PolicyConfig) (*Policy, error) {
+	p := &Policy{
+		rules:        make(map[string]*Rule, len(cfg.Rules)),
+		denyUnlisted: cfg.DenyUnlisted,
+	}
+	for _, r := range cfg.Rules {
+		if r.Resource == "" || len(r.AllOf)+len(r.AnyOf) == 0 {
+			return nil, ErrAuthPolicyInvalid(r.Resource)
+		}
+		if _, ok := p.rules[r.Resource]; ok {
+			return nil, ErrAuthPolicyInvalid(r.Resource)
+		}
+		p.rules[r.Resource] = r
+	}
+	return p, nil
+}
+
+// Check checks if the caller taken from the context has access to the resource
+func (p *Policy) Check(ctx context.Context, resource string) error {
+	r, ok := p.rules[resource]
+	if !ok {
+		if p.denyUnlisted {
+			return ErrAuthAccessDenied(ctx, resource)
+		}
+		return nil
+	}
+	roles := callerRoles(ctx)
+	if len(r.AllOf) > 0 && !hasAll(roles, r.AllOf) {
+		return ErrAuthAccessDenied(ctx, resource)
+	}
+	if len(r.AnyOf) > 0 && !hasAny(roles, r.AnyOf) {
+		return ErrAuthAccessDenied(ctx, resource)
+	}
+	return nil
+}
+
+// HasRoles checks if the caller has all the roles
+func HasRoles(ctx context.Context, roles ...string) bool {
+	return hasAll(callerRoles(ctx), roles)
+}
+
+// HasAnyRole checks if the caller has at least one of the roles
+func HasAnyRole(ctx context.Context, roles ...string) bool {
+	return hasAny(callerRoles(ctx), roles)
+}
+
+// RequireRoles returns access denied error if the caller doesn't have all the roles
+func RequireRoles(ctx context.Context, resource string, roles ...string) error {
+	if !HasRoles(ctx, roles...) {
+		return ErrAuthAccessDenied(ctx, resource)
+	}
+	return nil
+}
+
+// RequireAnyRole returns access denied error if the caller doesn't have any of the roles
+func RequireAnyRole(ctx context.Context, resource string, roles ...string) error {
+	if !HasAnyRole(ctx, roles...) {
+		return ErrAuthAccessDenied(ctx, resource)
+	}
+	return nil
+}
+
+func callerRoles(ctx context.Context) map[string]struct{} {
+	res := make(map[string]struct{})
+	if rCtx, ok := kitContext.Request(ctx); ok {
+		for _, r := range rCtx.GetRoles() {
+			res[r] = struct{}{}
+		}
+	}
+	return res
+}
+
+func hasAll(roles map[string]struct{}, required []string) bool {
+	for _, r := range required {
+		if _, ok := roles[r]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func hasAny(roles map[string]struct{}, required []string) bool {
+	for _, r := range required {
+		if _, ok := roles[r]; ok {
+			return true
+		}
+	}
+	return false
+}
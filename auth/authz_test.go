@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	kitContext "github.com/exluap/kit/context"
+	"github.com/exluap/kit/er"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"net/http"
+	"testing"
+)
+
+func ctxWithRoles(roles ...string) context.Context {
+	return kitContext.NewRequestCtx().Rest().WithRoles(roles...).ToContext(context.Background())
+}
+
+func Test_Policy_Check(t *testing.T) {
+	p, err := NewPolicy(&PolicyConfig{Rules: []*Rule{
+		{Resource: "/pkg.Svc/Delete", AllOf: []string{"admin", "writer"}},
+		{Resource: "/pkg.Svc/Get", AnyOf: []string{"admin", "reader"}},
+	}})
+	assert.Nil(t, err)
+
+	assert.Nil(t, p.Check(ctxWithRoles("admin", "writer"), "/pkg.Svc/Delete"))
+	assert.Nil(t, p.Check(ctxWithRoles("reader"), "/pkg.Svc/Get"))
+	assert.Nil(t, p.Check(ctxWithRoles(), "/pkg.Svc/Unlisted"))
+
+	err = p.Check(ctxWithRoles("admin"), "/pkg.Svc/Delete")
+	assert.True(t, er.HasCode(err, ErrCodeAuthAccessDenied))
+	appErr, _ := er.Is(err)
+	assert.Equal(t, uint32(http.StatusForbidden), *appErr.HttpStatus())
+	assert.Equal(t, uint32(codes.PermissionDenied), *appErr.GrpcStatus())
+
+	assert.Error(t, p.Check(context.Background(), "/pkg.Svc/Get"))
+}
+
+func Test_Policy_WhenDenyUnlisted(t *testing.T) {
+	p, err := NewPolicy(&PolicyConfig{DenyUnlisted: true})
+	assert.Nil(t, err)
+	assert.Error(t, p.Check(ctxWithRoles("admin"), "/pkg.Svc/Unlisted"))
+}
+
+func Test_Policy_WhenInvalid(t *testing.T) {
+	_, err := NewPolicy(&PolicyConfig{Rules: []*Rule{{Resource: "/pkg.Svc/Get"}}})
+	assert.True(t, er.HasCode(err, ErrCodeAuthPolicyInvalid))
+	_, err = NewPolicy(&PolicyConfig{Rules: []*Rule{
+		{Resource: "/pkg.Svc/Get", AnyOf: []string{"a"}},
+		{Resource: "/pkg.Svc/Get", AnyOf: []string{"b"}},
+	}})
+	assert.True(t, er.HasCode(err, ErrCodeAuthPolicyInvalid))
+}
+
+func Test_RequireRoles(t *testing.T) {
+	ctx := ctxWithRoles("a", "b")
+	assert.Nil(t, RequireRoles(ctx, "res", "a", "b"))
+	assert.Error(t, RequireRoles(ctx, "res", "a", "c"))
+	assert.Nil(t, RequireAnyRole(ctx, "res", "c", "b"))
+	assert.Error(t, RequireAnyRole(ctx, "res", "c", "d"))
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	"github.com/exluap/kit/er"
+	"google.golang.org/grpc/codes"
+	"net/http"
+)
+
+const (
+	ErrCodeAuthAccessDenied  = "AUTH-001"
+	ErrCodeAuthPolicyInvalid = "AUTH-002"
+)
+
+var (
+	ErrAuthAccessDenied = func(ctx context.Context, resource string) error {
+		return er.WithBuilder(ErrCodeAuthAccessDenied, "access denied").F(er.FF{"resource": resource}).C(ctx).
+			HttpSt(http.StatusForbidden).GrpcSt(uint32(codes.PermissionDenied)).Err()
+	}
+	ErrAuthPolicyInvalid = func(resource string) error {
+		return er.WithBuilder(ErrCodeAuthPolicyInvalid, "invalid policy rule").F(er.FF{"resource": resource}).Err()
+	}
+)
+
+func init() {
+	er.Register(
+		er.CatalogEntry{Code: ErrCodeAuthAccessDenied, Message: "access denied", HttpStatus: er.St(http.StatusForbidden), GrpcStatus: er.St(uint32(codes.PermissionDenied))},
+		er.CatalogEntry{Code: ErrCodeAuthPolicyInvalid, Message: "invalid policy rule"},
+	)
+}
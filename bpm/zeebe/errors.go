@@ -41,3 +41,20 @@ var (
 	ErrZeebeVarsAsMap = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeZeebeVarsAsMap, "").Err() }
 	ErrZeebeSend      = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeZeebeSend, "").Err() }
 )
+
+func init() {
+	er.Register(
+		er.CatalogEntry{Code: ErrCodeZeebeSend, Message: "zeebe command send failed"},
+		er.CatalogEntry{Code: ErrCodeZeebeClose, Message: "zeebe client close failed"},
+		er.CatalogEntry{Code: ErrCodeZeebeConnInvalidParams, Message: "invalid params"},
+		er.CatalogEntry{Code: ErrCodeZeebeNewClient, Message: "zeebe client creation failed"},
+		er.CatalogEntry{Code: ErrCodeZeebeConnInvalidHandler, Message: "invalid handler"},
+		er.CatalogEntry{Code: ErrCodeZeebeStartProcess, Message: "process start failed"},
+		er.CatalogEntry{Code: ErrCodeZeebeSendMessage, Message: "message send failed"},
+		er.CatalogEntry{Code: ErrCodeZeebeSendError, Message: "error send failed"},
+		er.CatalogEntry{Code: ErrCodeZeebeVarsFromMap, Message: "variables from map failed"},
+		er.CatalogEntry{Code: ErrCodeZeebeVarsAsMap, Message: "variables as map failed"},
+		er.CatalogEntry{Code: ErrCodeZeebeCtxInvalid, Message: "invalid _ctx vars"},
+		er.CatalogEntry{Code: ErrCodeZeebeCtxNotFound, Message: "not found _ctx vars"},
+	)
+}
@@ -75,7 +75,8 @@ func (u *Utils) CtxFromVars(vars map[string]interface{}) (context.Context, error
 
 func (u *Utils) CtxToVars(ctx context.Context, vars map[string]interface{}) error {
 	if r, ok := kitContext.Request(ctx); ok {
-		vars["_ctx"] = r
+		// process is a new hop, so it's passed with a child span
+		vars["_ctx"] = r.ChildSpan()
 		return nil
 	}
 	return ErrZeebeCtxNotFound()
@@ -7,5 +7,11 @@ const (
 )
 
 var (
-	ErrRedisPingErr = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeRedisPingErr, "").Err() }
+	ErrRedisPingErr = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeRedisPingErr, "").Retryable().Err() }
 )
+
+func init() {
+	er.Register(
+		er.CatalogEntry{Code: ErrCodeRedisPingErr, Message: "redis ping failed", Retryable: true},
+	)
+}
@@ -17,8 +17,9 @@ func (s *BaseService) Publish(ctx context.Context, o interface{}, qt queue.Queue
 
 	m := &queue.Message{Payload: o}
 
+	// message is a new hop, so it's passed with a child span
 	if rCtx, ok := kitContext.Request(ctx); ok {
-		m.Ctx = rCtx
+		m.Ctx = rCtx.ChildSpan()
 	} else {
 		return ErrBaseModelCannotPublishToQueue(ctx, topic)
 	}
@@ -14,3 +14,9 @@ var (
 		return er.WithBuilder(ErrCodeBaseModelCannotPublishToQueue, "cannot publish to topic").C(ctx).F(er.FF{"topic": topic}).Err()
 	}
 )
+
+func init() {
+	er.Register(
+		er.CatalogEntry{Code: ErrCodeBaseModelCannotPublishToQueue, Message: "cannot publish to topic"},
+	)
+}
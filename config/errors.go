@@ -63,3 +63,25 @@ var (
 	ErrConfigInit = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeConfigInit, "").Err() }
 	ErrConfigLoad = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeConfigLoad, "").Err() }
 )
+
+func init() {
+	er.Register(
+		er.CatalogEntry{Code: ErrCodeEnvRootPathNotSet, Message: "root path env variable isn't set"},
+		er.CatalogEntry{Code: ErrCodeEnvFileOpening, Message: "env file opening failed"},
+		er.CatalogEntry{Code: ErrCodeEnvNewConfig, Message: "env config creation failed"},
+		er.CatalogEntry{Code: ErrCodeEnvLoad, Message: "env loading failed"},
+		er.CatalogEntry{Code: ErrCodeServiceConfigNotSpecified, Message: "config for service isn't specified"},
+		er.CatalogEntry{Code: ErrCodeConfigPortEnvEmpty, Message: "env var CONFIG_CFG_GRPC_PORT is empty"},
+		er.CatalogEntry{Code: ErrCodeConfigHostEnvEmpty, Message: "env var CONFIG_CFG_GRPC_HOST is empty"},
+		er.CatalogEntry{Code: ErrCodeConfigTimeout, Message: "not ready within timeout"},
+		er.CatalogEntry{Code: ErrCodeEnvFileLoadingPath, Message: ".env loading"},
+		er.CatalogEntry{Code: ErrCodeConfigFileNotFound, Message: "config file not found"},
+		er.CatalogEntry{Code: ErrCodeConfigFileOpen, Message: "config file open failed"},
+		er.CatalogEntry{Code: ErrCodeConfigPathEmpty, Message: "config path empty"},
+		er.CatalogEntry{Code: ErrCodeEnvFileNotFound, Message: "env file not found"},
+		er.CatalogEntry{Code: ErrCodeEnvFileOpen, Message: "env file open failed"},
+		er.CatalogEntry{Code: ErrCodeConfigInit, Message: "config init failed"},
+		er.CatalogEntry{Code: ErrCodeConfigLoad, Message: "config load failed"},
+		er.CatalogEntry{Code: ErrCodeConfigTargetObjectInvalidType, Message: "target object must be pointer on struct"},
+	)
+}
@@ -2,7 +2,6 @@ package context
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"github.com/exluap/kit"
 	"github.com/mitchellh/mapstructure"
@@ -31,6 +30,18 @@ type RequestContext struct {
 	Caller string `json:"_ctx.cl"`
 	// Roles list of roles
 	Roles []string `json:"_ctx.rl"`
+	// Lang preferred locales of the caller (e.g. en_US, ru)
+	Lang []string `json:"_ctx.lang,omitempty"`
+	// TraceId W3C trace ID (32 hex chars)
+	TraceId string `json:"_ctx.tid,omitempty"`
+	// SpanId W3C span ID of the current hop (16 hex chars)
+	SpanId string `json:"_ctx.spid,omitempty"`
+	// ParentSpanId span ID of the calling hop
+	ParentSpanId string `json:"_ctx.pspid,omitempty"`
+	// Sampled W3C sampled flag
+	Sampled bool `json:"_ctx.smp,omitempty"`
+	// Baggage free-form key-values propagated across all the hops
+	Baggage map[string]string `json:"_ctx.bg,omitempty"`
 }
 
 func NewRequestCtx() *RequestContext {
@@ -61,6 +72,10 @@ func (r *RequestContext) GetUsername() string {
 	return r.Un
 }
 
+func (r *RequestContext) GetLang() []string {
+	return r.Lang
+}
+
 func (r *RequestContext) Empty() *RequestContext {
 	return &RequestContext{}
 }
@@ -116,6 +131,11 @@ func (r *RequestContext) WithRoles(roles ...string) *RequestContext {
 	return r
 }
 
+func (r *RequestContext) WithLang(locales ...string) *RequestContext {
+	r.Lang = locales
+	return r
+}
+
 func (r *RequestContext) ToContext(parent context.Context) context.Context {
 	if parent == nil {
 		parent = context.Background()
@@ -125,12 +145,18 @@ func (r *RequestContext) ToContext(parent context.Context) context.Context {
 
 func (r *RequestContext) ToMap() map[string]interface{} {
 	return map[string]interface{}{
-		"_ctx.rid": r.Rid,
-		"_ctx.sid": r.Sid,
-		"_ctx.uid": r.Uid,
-		"_ctx.un":  r.Un,
-		"_ctx.cl":  r.Caller,
-		"_ctx.rl":  r.Roles,
+		"_ctx.rid":   r.Rid,
+		"_ctx.sid":   r.Sid,
+		"_ctx.uid":   r.Uid,
+		"_ctx.un":    r.Un,
+		"_ctx.cl":    r.Caller,
+		"_ctx.rl":    r.Roles,
+		"_ctx.lang":  r.Lang,
+		"_ctx.tid":   r.TraceId,
+		"_ctx.spid":  r.SpanId,
+		"_ctx.pspid": r.ParentSpanId,
+		"_ctx.smp":   r.Sampled,
+		"_ctx.bg":    r.Baggage,
 	}
 }
 
@@ -148,30 +174,30 @@ func MustRequest(context context.Context) (*RequestContext, error) {
 	return &RequestContext{}, errors.New("context is invalid")
 }
 
+// FromContextToGrpcMD puts request context to gRPC metadata
+// if context is traced, a child span is propagated, so the callee's parent span is the current span
 func FromContextToGrpcMD(ctx context.Context) (metadata.MD, bool) {
 	if r, ok := Request(ctx); ok {
-		rm, _ := json.Marshal(*r)
-		return metadata.Pairs("rq-bin", string(rm)), true
+		return EncodeGrpcMD(r.ChildSpan()), true
 	}
 	return metadata.Pairs(), false
 }
 
+// FromGrpcMD takes request context from gRPC metadata in lenient mode
+// use DecodeGrpcMD if decoding problems must be reported
 func FromGrpcMD(ctx context.Context, md metadata.MD) context.Context {
-
-	if rqb, ok := md["rq-bin"]; ok {
-		if len(rqb) > 0 {
-			rm := []byte(rqb[0])
-			rq := &RequestContext{}
-			_ = json.Unmarshal(rm, rq)
-			return context.WithValue(ctx, requestContextKey{}, rq)
-		}
-	}
+	ctx, _ = DecodeGrpcMD(ctx, md, DecodeLenient)
 	return ctx
 }
 
 func FromMap(ctx context.Context, mp map[string]interface{}) (context.Context, error) {
 	var r *RequestContext
-	err := mapstructure.Decode(mp, &r)
+	// keys of the map are the same as json keys (see ToMap)
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{TagName: "json", Result: &r, WeaklyTypedInput: true})
+	if err != nil {
+		return nil, err
+	}
+	err = decoder.Decode(mp)
 	if err != nil {
 		return nil, err
 	}
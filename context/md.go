@@ -0,0 +1,204 @@
+package context
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc/metadata"
+	"sort"
+	"sync"
+)
+
+const (
+	// GrpcMDKey metadata key the request context is passed with
+	// "-bin" suffix makes gRPC transfer it base64 encoded, so any bytes are safe
+	GrpcMDKey = "rq-bin"
+	// MDVersion current version of request context encoding
+	// version 1 is legacy encoding without version field
+	MDVersion = 2
+)
+
+// DecodeMode specifies how problems of the incoming metadata are treated
+type DecodeMode int
+
+const (
+	// DecodeLenient takes everything which can be taken, unknown versions are decoded by known fields, exceeded limits are truncated
+	// malformed metadata is still reported, but the best effort context is returned
+	DecodeLenient DecodeMode = iota
+	// DecodeStrict reports any problem and returns the parent context untouched
+	DecodeStrict
+)
+
+var (
+	ErrMDMalformed           = errors.New("request context metadata is malformed")
+	ErrMDVersionNotSupported = errors.New("request context metadata version isn't supported")
+	ErrMDLimitExceeded       = errors.New("request context metadata limit exceeded")
+)
+
+// MDLimits restricts request context passed in gRPC metadata
+type MDLimits struct {
+	MaxSize         int // MaxSize - max size of encoded request context in bytes
+	MaxRoles        int // MaxRoles - max number of roles
+	MaxBaggageItems int // MaxBaggageItems - max number of baggage items
+	MaxBaggageSize  int // MaxBaggageSize - max total size of baggage keys and values in bytes
+}
+
+// DefaultMDLimits limits applied if not changed by SetMDLimits
+var DefaultMDLimits = MDLimits{
+	MaxSize:         16 * 1024,
+	MaxRoles:        64,
+	MaxBaggageItems: 32,
+	MaxBaggageSize:  4 * 1024,
+}
+
+var (
+	mdLimitsMu sync.RWMutex
+	mdLimits   = DefaultMDLimits
+)
+
+// SetMDLimits changes limits applied when request context is encoded to and decoded from gRPC metadata
+func SetMDLimits(limits MDLimits) {
+	mdLimitsMu.Lock()
+	defer mdLimitsMu.Unlock()
+	mdLimits = limits
+}
+
+func getMDLimits() MDLimits {
+	mdLimitsMu.RLock()
+	defer mdLimitsMu.RUnlock()
+	return mdLimits
+}
+
+// mdEnvelope is a versioned request context encoding
+// services of the previous version ignore the version field, so they are still able to decode it
+type mdEnvelope struct {
+	V int `json:"_ctx.v,omitempty"`
+	*RequestContext
+}
+
+// MarshalRequestContext encodes request context with the current version
+// roles and baggage exceeding limits are truncated, so strict receivers don't reject it
+func MarshalRequestContext(r *RequestContext) []byte {
+	r, _ = applyMDLimits(r, getMDLimits(), false)
+	rm, _ := json.Marshal(mdEnvelope{V: MDVersion, RequestContext: r})
+	return rm
+}
+
+// UnmarshalRequestContext decodes request context encoded by MarshalRequestContext or by legacy encoding
+// in lenient mode, unknown versions are decoded by known fields and exceeded limits are truncated
+func UnmarshalRequestContext(data []byte, mode DecodeMode) (*RequestContext, error) {
+
+	limits := getMDLimits()
+	if limits.MaxSize > 0 && len(data) > limits.MaxSize {
+		return nil, fmt.Errorf("%w: size %d", ErrMDLimitExceeded, len(data))
+	}
+
+	env := mdEnvelope{RequestContext: NewRequestCtx()}
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMDMalformed, err)
+	}
+
+	if env.V > MDVersion && mode == DecodeStrict {
+		return nil, fmt.Errorf("%w: %d", ErrMDVersionNotSupported, env.V)
+	}
+
+	return applyMDLimits(env.RequestContext, limits, mode == DecodeStrict)
+}
+
+// EncodeGrpcMD encodes request context to gRPC metadata
+func EncodeGrpcMD(r *RequestContext) metadata.MD {
+	md := metadata.Pairs(GrpcMDKey, string(MarshalRequestContext(r)))
+	if tp := r.Traceparent(); tp != "" {
+		md.Set(TraceparentHeader, tp)
+	}
+	return md
+}
+
+// DecodeGrpcMD decodes request context from gRPC metadata and puts it to the context
+// if there is no request context in metadata, W3C trace context is taken if passed
+// in lenient mode, returned context is usable even if error is returned, so the error is supposed to be just logged
+// in strict mode, the parent context is returned along with the error, so the call is supposed to be rejected
+func DecodeGrpcMD(ctx context.Context, md metadata.MD, mode DecodeMode) (context.Context, error) {
+
+	traceCtx := func() *RequestContext {
+		if tp := md.Get(TraceparentHeader); len(tp) > 0 {
+			if rq, err := NewRequestCtx().WithTraceparent(tp[0]); err == nil {
+				return rq
+			}
+		}
+		return nil
+	}
+
+	values := md.Get(GrpcMDKey)
+	if len(values) == 0 || values[0] == "" {
+		// callers not using kit might pass W3C trace context only
+		if rq := traceCtx(); rq != nil {
+			return rq.ToContext(ctx), nil
+		}
+		return ctx, nil
+	}
+
+	rq, err := UnmarshalRequestContext([]byte(values[0]), mode)
+	if err != nil {
+		if mode == DecodeStrict {
+			return ctx, err
+		}
+		// lenient mode falls back to trace context
+		if rq = traceCtx(); rq == nil {
+			rq = NewRequestCtx()
+		}
+		return rq.ToContext(ctx), err
+	}
+	return rq.ToContext(ctx), nil
+}
+
+// applyMDLimits checks roles and baggage against limits
+// if strict, it returns error when limits are exceeded, otherwise the copy of the request context with truncated roles and baggage is returned
+func applyMDLimits(r *RequestContext, limits MDLimits, strict bool) (*RequestContext, error) {
+
+	rolesExceeded := limits.MaxRoles > 0 && len(r.Roles) > limits.MaxRoles
+
+	baggageSize := 0
+	for k, v := range r.Baggage {
+		baggageSize += len(k) + len(v)
+	}
+	baggageExceeded := (limits.MaxBaggageItems > 0 && len(r.Baggage) > limits.MaxBaggageItems) ||
+		(limits.MaxBaggageSize > 0 && baggageSize > limits.MaxBaggageSize)
+
+	if !rolesExceeded && !baggageExceeded {
+		return r, nil
+	}
+
+	if strict {
+		if rolesExceeded {
+			return nil, fmt.Errorf("%w: roles %d", ErrMDLimitExceeded, len(r.Roles))
+		}
+		return nil, fmt.Errorf("%w: baggage items %d, size %d", ErrMDLimitExceeded, len(r.Baggage), baggageSize)
+	}
+
+	c := *r
+	if rolesExceeded {
+		c.Roles = append([]string{}, r.Roles[:limits.MaxRoles]...)
+	}
+	if baggageExceeded {
+		// keys are sorted to truncate deterministically
+		keys := make([]string, 0, len(r.Baggage))
+		for k := range r.Baggage {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		c.Baggage = make(map[string]string)
+		size := 0
+		for _, k := range keys {
+			itemSize := len(k) + len(r.Baggage[k])
+			if (limits.MaxBaggageItems > 0 && len(c.Baggage) >= limits.MaxBaggageItems) ||
+				(limits.MaxBaggageSize > 0 && size+itemSize > limits.MaxBaggageSize) {
+				break
+			}
+			c.Baggage[k] = r.Baggage[k]
+			size += itemSize
+		}
+	}
+	return &c, nil
+}
@@ -0,0 +1,90 @@
+package context
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"testing"
+)
+
+func Test_GrpcMD_Versioned(t *testing.T) {
+	r := NewRequestCtx().Rest().WithNewRequestId().WithUser("uid", "un").WithRoles("admin")
+	md := EncodeGrpcMD(r)
+
+	var raw map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(md.Get(GrpcMDKey)[0]), &raw))
+	assert.Equal(t, float64(MDVersion), raw["_ctx.v"])
+
+	ctx, err := DecodeGrpcMD(context.Background(), md, DecodeStrict)
+	assert.Nil(t, err)
+	rq, ok := Request(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, r.Rid, rq.Rid)
+	assert.Equal(t, []string{"admin"}, rq.Roles)
+}
+
+func Test_GrpcMD_WhenLegacy(t *testing.T) {
+	rm, _ := json.Marshal(NewRequestCtx().Rest().WithRequestId("rid"))
+	ctx, err := DecodeGrpcMD(context.Background(), metadata.Pairs(GrpcMDKey, string(rm)), DecodeStrict)
+	assert.Nil(t, err)
+	rq, _ := Request(ctx)
+	assert.Equal(t, "rid", rq.Rid)
+}
+
+func Test_GrpcMD_WhenMalformed(t *testing.T) {
+	md := metadata.Pairs(GrpcMDKey, "{not json", TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	ctx, err := DecodeGrpcMD(context.Background(), md, DecodeStrict)
+	assert.True(t, errors.Is(err, ErrMDMalformed))
+	_, ok := Request(ctx)
+	assert.False(t, ok)
+
+	ctx, err = DecodeGrpcMD(context.Background(), md, DecodeLenient)
+	assert.True(t, errors.Is(err, ErrMDMalformed))
+	rq, ok := Request(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", rq.TraceId)
+}
+
+func Test_GrpcMD_WhenNewerVersion(t *testing.T) {
+	md := metadata.Pairs(GrpcMDKey, fmt.Sprintf(`{"_ctx.v":%d,"_ctx.rid":"rid","_ctx.new":"x"}`, MDVersion+1))
+
+	_, err := DecodeGrpcMD(context.Background(), md, DecodeStrict)
+	assert.True(t, errors.Is(err, ErrMDVersionNotSupported))
+
+	ctx, err := DecodeGrpcMD(context.Background(), md, DecodeLenient)
+	assert.Nil(t, err)
+	rq, _ := Request(ctx)
+	assert.Equal(t, "rid", rq.Rid)
+}
+
+func Test_GrpcMD_Limits(t *testing.T) {
+	SetMDLimits(MDLimits{MaxSize: 1024, MaxRoles: 2, MaxBaggageItems: 2, MaxBaggageSize: 100})
+	defer SetMDLimits(DefaultMDLimits)
+
+	r := NewRequestCtx().WithRoles("r1", "r2", "r3").WithBaggage("a", "1").WithBaggage("b", "2").WithBaggage("c", "3")
+
+	// sender truncates
+	rq, _ := Request(FromGrpcMD(context.Background(), EncodeGrpcMD(r)))
+	assert.Equal(t, []string{"r1", "r2"}, rq.Roles)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, rq.Baggage)
+	assert.Len(t, r.Roles, 3)
+
+	// receiver checks
+	rm, _ := json.Marshal(r)
+	md := metadata.Pairs(GrpcMDKey, string(rm))
+	_, err := DecodeGrpcMD(context.Background(), md, DecodeStrict)
+	assert.True(t, errors.Is(err, ErrMDLimitExceeded))
+	ctx, err := DecodeGrpcMD(context.Background(), md, DecodeLenient)
+	assert.Nil(t, err)
+	rq, _ = Request(ctx)
+	assert.Len(t, rq.Roles, 2)
+
+	big := NewRequestCtx().WithBaggage("k", string(make([]byte, 2048)))
+	rm, _ = json.Marshal(big)
+	_, err = DecodeGrpcMD(context.Background(), metadata.Pairs(GrpcMDKey, string(rm)), DecodeLenient)
+	assert.True(t, errors.Is(err, ErrMDLimitExceeded))
+}
@@ -0,0 +1,171 @@
+package context
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	TraceparentHeader = "traceparent" // TraceparentHeader W3C trace context header
+	BaggageHeader     = "baggage"     // BaggageHeader W3C baggage header
+)
+
+// WithTrace sets trace ID and span ID
+func (r *RequestContext) WithTrace(traceId, spanId string, sampled bool) *RequestContext {
+	r.TraceId = traceId
+	r.SpanId = spanId
+	r.Sampled = sampled
+	return r
+}
+
+// WithNewTrace starts a new sampled trace
+func (r *RequestContext) WithNewTrace() *RequestContext {
+	r.TraceId = newHexId(16)
+	r.SpanId = newHexId(8)
+	r.ParentSpanId = ""
+	r.Sampled = true
+	return r
+}
+
+// WithBaggage adds a baggage item
+func (r *RequestContext) WithBaggage(key, value string) *RequestContext {
+	if r.Baggage == nil {
+		r.Baggage = make(map[string]string)
+	}
+	r.Baggage[key] = value
+	return r
+}
+
+func (r *RequestContext) GetTraceId() string {
+	return r.TraceId
+}
+
+func (r *RequestContext) GetSpanId() string {
+	return r.SpanId
+}
+
+func (r *RequestContext) GetParentSpanId() string {
+	return r.ParentSpanId
+}
+
+func (r *RequestContext) GetBaggage() map[string]string {
+	return r.Baggage
+}
+
+// ChildSpan returns a copy of the request context for an outgoing hop
+// the current span becomes the parent one and a new span ID is generated
+// if context isn't traced, the copy is returned as is
+func (r *RequestContext) ChildSpan() *RequestContext {
+	c := *r
+	if len(r.Roles) > 0 {
+		c.Roles = append([]string{}, r.Roles...)
+	}
+	if len(r.Baggage) > 0 {
+		c.Baggage = make(map[string]string, len(r.Baggage))
+		for k, v := range r.Baggage {
+			c.Baggage[k] = v
+		}
+	}
+	if c.TraceId != "" {
+		c.ParentSpanId = r.SpanId
+		c.SpanId = newHexId(8)
+	}
+	return &c
+}
+
+// Traceparent returns W3C traceparent value, empty if context isn't traced
+func (r *RequestContext) Traceparent() string {
+	if r.TraceId == "" || r.SpanId == "" {
+		return ""
+	}
+	flags := "00"
+	if r.Sampled {
+		flags = "01"
+	}
+	return "00-" + r.TraceId + "-" + r.SpanId + "-" + flags
+}
+
+// WithTraceparent takes trace from W3C traceparent value
+// span ID from traceparent becomes the parent span and a new span ID is generated for the current hop
+func (r *RequestContext) WithTraceparent(traceparent string) (*RequestContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		!isHexId(parts[1], 16) || !isHexId(parts[2], 8) || len(parts[3]) != 2 {
+		return r, errors.New("invalid traceparent")
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return r, errors.New("invalid traceparent")
+	}
+	r.TraceId = parts[1]
+	r.ParentSpanId = parts[2]
+	r.SpanId = newHexId(8)
+	r.Sampled = flags[0]&0x01 == 0x01
+	return r, nil
+}
+
+// newHexId generates a random hex ID of the given size in bytes
+func newHexId(size int) string {
+	b := make([]byte, size)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// isHexId checks if id is a valid W3C id of the given size in bytes (lowercase hex, not all zeros)
+func isHexId(id string, size int) bool {
+	if len(id) != size*2 || strings.ToLower(id) != id {
+		return false
+	}
+	b, err := hex.DecodeString(id)
+	if err != nil {
+		return false
+	}
+	for _, c := range b {
+		if c != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// BaggageValue returns W3C baggage header value, empty if there is no baggage
+func (r *RequestContext) BaggageValue() string {
+	if len(r.Baggage) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(r.Baggage))
+	for k := range r.Baggage {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	items := make([]string, 0, len(keys))
+	for _, k := range keys {
+		items = append(items, url.PathEscape(k)+"="+url.PathEscape(r.Baggage[k]))
+	}
+	return strings.Join(items, ",")
+}
+
+// WithBaggageValue adds baggage items from W3C baggage header value
+// item properties are ignored, malformed items are skipped
+func (r *RequestContext) WithBaggageValue(baggage string) *RequestContext {
+	for _, item := range strings.Split(baggage, ",") {
+		if i := strings.Index(item, ";"); i >= 0 {
+			item = item[:i]
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		k, errK := url.PathUnescape(strings.TrimSpace(kv[0]))
+		v, errV := url.PathUnescape(strings.TrimSpace(kv[1]))
+		if errK != nil || errV != nil || k == "" {
+			continue
+		}
+		r.WithBaggage(k, v)
+	}
+	return r
+}
@@ -0,0 +1,79 @@
+package context
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	"testing"
+)
+
+func Test_Traceparent(t *testing.T) {
+	r, err := NewRequestCtx().WithTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.Nil(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", r.TraceId)
+	assert.Equal(t, "00f067aa0ba902b7", r.ParentSpanId)
+	assert.Len(t, r.SpanId, 16)
+	assert.True(t, r.Sampled)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+r.SpanId+"-01", r.Traceparent())
+}
+
+func Test_Traceparent_WhenInvalid(t *testing.T) {
+	for _, tp := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		_, err := NewRequestCtx().WithTraceparent(tp)
+		assert.Error(t, err, tp)
+	}
+}
+
+func Test_ChildSpan(t *testing.T) {
+	r := NewRequestCtx().Rest().WithNewRequestId().WithNewTrace().WithBaggage("tenant", "t1")
+	c := r.ChildSpan()
+	assert.Equal(t, r.TraceId, c.TraceId)
+	assert.Equal(t, r.SpanId, c.ParentSpanId)
+	assert.NotEqual(t, r.SpanId, c.SpanId)
+	c.WithBaggage("tenant", "t2")
+	assert.Equal(t, "t1", r.Baggage["tenant"])
+}
+
+func Test_GrpcMD_WithTrace(t *testing.T) {
+	r := NewRequestCtx().Rest().WithNewRequestId().WithNewTrace().WithBaggage("tenant", "t1")
+	md, ok := FromContextToGrpcMD(r.ToContext(context.Background()))
+	assert.True(t, ok)
+	assert.NotEmpty(t, md.Get(TraceparentHeader))
+
+	rq, ok := Request(FromGrpcMD(context.Background(), md))
+	assert.True(t, ok)
+	assert.Equal(t, r.Rid, rq.Rid)
+	assert.Equal(t, r.TraceId, rq.TraceId)
+	assert.Equal(t, r.SpanId, rq.ParentSpanId)
+	assert.Equal(t, "t1", rq.Baggage["tenant"])
+}
+
+func Test_GrpcMD_WhenTraceparentOnly(t *testing.T) {
+	md := metadata.Pairs(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	rq, ok := Request(FromGrpcMD(context.Background(), md))
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", rq.TraceId)
+	assert.False(t, rq.Sampled)
+}
+
+func Test_FromMap_WithTrace(t *testing.T) {
+	r := NewRequestCtx().Job().WithNewRequestId().WithRoles("admin").WithNewTrace().WithBaggage("tenant", "t1")
+	ctx, err := FromMap(context.Background(), r.ToMap())
+	assert.Nil(t, err)
+	rq, ok := Request(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, r, rq)
+}
+
+func Test_BaggageValue(t *testing.T) {
+	r := NewRequestCtx().WithBaggageValue("tenant=t1, user%20type=vip;prop=1,broken, =empty")
+	assert.Equal(t, map[string]string{"tenant": "t1", "user type": "vip"}, r.Baggage)
+	assert.Equal(t, "tenant=t1,user%20type=vip", r.BaggageValue())
+	assert.Equal(t, "", NewRequestCtx().BaggageValue())
+}
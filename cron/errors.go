@@ -11,3 +11,9 @@ var (
 		return er.WrapWithBuilder(cause, ErrCodeCronStart, "").F(er.FF{"name": name}).Err()
 	}
 )
+
+func init() {
+	er.Register(
+		er.CatalogEntry{Code: ErrCodeCronStart, Message: "cron start failed"},
+	)
+}
@@ -27,3 +27,15 @@ var (
 		return er.WrapWithBuilder(cause, ErrCodeGooseMigrationUnLock, "unlocking after migration").Err()
 	}
 )
+
+func init() {
+	er.Register(
+		er.CatalogEntry{Code: ErrCodeGooseMigrationUp, Message: "migration up failed"},
+		er.CatalogEntry{Code: ErrCodeGooseMigrationGetVer, Message: "migration version failed"},
+		er.CatalogEntry{Code: ErrCodePostgresOpen, Message: "postgres open failed"},
+		er.CatalogEntry{Code: ErrCodeGooseFolderNotFound, Message: "folder not found"},
+		er.CatalogEntry{Code: ErrCodeGooseFolderOpen, Message: "folder open"},
+		er.CatalogEntry{Code: ErrCodeGooseMigrationLock, Message: "locking before migration"},
+		er.CatalogEntry{Code: ErrCodeGooseMigrationUnLock, Message: "unlocking after migration"},
+	)
+}
@@ -0,0 +1,137 @@
+package er
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CatalogEntry describes an error code registered in the catalog
+type CatalogEntry struct {
+	Code           string  `json:"code"`                 // Code is error code
+	Message        string  `json:"message"`              // Message is default error description
+	HttpStatus     *uint32 `json:"httpStatus,omitempty"` // HttpStatus is HTTP status returned to clients
+	GrpcStatus     *uint32 `json:"grpcStatus,omitempty"` // GrpcStatus is gRPC status code
+	TranslationKey string  `json:"translationKey"`       // TranslationKey is a key used by clients to translate error
+	Retryable      bool    `json:"retryable,omitempty"`  // Retryable indicates error is transient
+}
+
+// catalog keeps all registered error codes
+type catalog struct {
+	sync.RWMutex
+	entries map[string]*CatalogEntry
+}
+
+var errCatalog = &catalog{entries: make(map[string]*CatalogEntry)}
+
+// TranslationKey returns translation key for the given code
+// if code is registered with explicit translation key, it's returned, otherwise it's built from the code
+func TranslationKey(code string) string {
+	if e, ok := Lookup(code); ok && e.TranslationKey != "" {
+		return e.TranslationKey
+	}
+	return defaultTranslationKey(code)
+}
+
+func defaultTranslationKey(code string) string {
+	return "errors.app.code." + strings.ReplaceAll(strings.ToLower(code), "-", ".")
+}
+
+// TryRegister registers entries in the catalog
+// it fails if any code is empty or already registered, in this case nothing is registered
+func TryRegister(entries ...CatalogEntry) error {
+	errCatalog.Lock()
+	defer errCatalog.Unlock()
+
+	batch := make(map[string]struct{}, len(entries))
+	for _, e := range entries {
+		if e.Code == "" {
+			return fmt.Errorf("error code is empty")
+		}
+		if _, ok := errCatalog.entries[e.Code]; ok {
+			return fmt.Errorf("error code %s is already registered", e.Code)
+		}
+		if _, ok := batch[e.Code]; ok {
+			return fmt.Errorf("error code %s is duplicated", e.Code)
+		}
+		batch[e.Code] = struct{}{}
+	}
+
+	for _, e := range entries {
+		e := e
+		if e.TranslationKey == "" {
+			e.TranslationKey = defaultTranslationKey(e.Code)
+		}
+		errCatalog.entries[e.Code] = &e
+	}
+	return nil
+}
+
+// Register registers entries in the catalog
+// it's supposed to be called from init() of packages declaring error codes, so it panics on duplicated code
+func Register(entries ...CatalogEntry) {
+	if err := TryRegister(entries...); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns registered entry by code
+func Lookup(code string) (CatalogEntry, bool) {
+	errCatalog.RLock()
+	defer errCatalog.RUnlock()
+	if e, ok := errCatalog.entries[code]; ok {
+		return *e, true
+	}
+	return CatalogEntry{}, false
+}
+
+// Catalog returns all registered entries ordered by code
+func Catalog() []CatalogEntry {
+	errCatalog.RLock()
+	defer errCatalog.RUnlock()
+	res := make([]CatalogEntry, 0, len(errCatalog.entries))
+	for _, e := range errCatalog.entries {
+		res = append(res, *e)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Code < res[j].Code })
+	return res
+}
+
+// ExportJson writes the whole catalog as JSON array
+func ExportJson(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(Catalog())
+}
+
+// ExportMarkdown writes the whole catalog as Markdown table
+func ExportMarkdown(w io.Writer) error {
+	if _, err := fmt.Fprintln(w, "|code|http|grpc|retryable|translation key|message|\n|----|----|----|---------|---------------|-------|"); err != nil {
+		return err
+	}
+	status := func(s *uint32) string {
+		if s == nil {
+			return ""
+		}
+		return fmt.Sprintf("%d", *s)
+	}
+	for _, e := range Catalog() {
+		msg := strings.ReplaceAll(e.Message, "|", "\\|")
+		retryable := ""
+		if e.Retryable {
+			retryable = "yes"
+		}
+		if _, err := fmt.Fprintf(w, "|%s|%s|%s|%s|%s|%s|\n", e.Code, status(e.HttpStatus), status(e.GrpcStatus), retryable, e.TranslationKey, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// St is a helper to specify status in CatalogEntry
+func St(status uint32) *uint32 {
+	return &status
+}
@@ -0,0 +1,66 @@
+package er
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// withTestCatalog replaces the global catalog with an empty one for the test
+// so the test registers its codes independently of other tests and repeated runs
+func withTestCatalog(t *testing.T) {
+	global := errCatalog
+	errCatalog = &catalog{entries: make(map[string]*CatalogEntry)}
+	t.Cleanup(func() { errCatalog = global })
+}
+
+func Test_Catalog_Register(t *testing.T) {
+	withTestCatalog(t)
+	Register(CatalogEntry{Code: "TST-CAT-001", Message: "first", HttpStatus: St(404)})
+	e, ok := Lookup("TST-CAT-001")
+	assert.True(t, ok)
+	assert.Equal(t, "first", e.Message)
+	assert.Equal(t, uint32(404), *e.HttpStatus)
+	assert.Equal(t, "errors.app.code.tst.cat.001", e.TranslationKey)
+}
+
+func Test_Catalog_WhenDuplicate(t *testing.T) {
+	withTestCatalog(t)
+	Register(CatalogEntry{Code: "TST-CAT-002", Message: "first"})
+	assert.Error(t, TryRegister(CatalogEntry{Code: "TST-CAT-002", Message: "second"}))
+	assert.Panics(t, func() { Register(CatalogEntry{Code: "TST-CAT-002"}) })
+	e, _ := Lookup("TST-CAT-002")
+	assert.Equal(t, "first", e.Message)
+}
+
+func Test_Catalog_WhenDuplicateWithinBatch(t *testing.T) {
+	withTestCatalog(t)
+	err := TryRegister(CatalogEntry{Code: "TST-CAT-003"}, CatalogEntry{Code: "TST-CAT-003"})
+	assert.Error(t, err)
+	_, ok := Lookup("TST-CAT-003")
+	assert.False(t, ok)
+}
+
+func Test_Catalog_TranslationKey(t *testing.T) {
+	withTestCatalog(t)
+	Register(CatalogEntry{Code: "TST-CAT-004", TranslationKey: "custom.key"})
+	assert.Equal(t, "custom.key", TranslationKey("TST-CAT-004"))
+	assert.Equal(t, "errors.app.code.tst.unknown", TranslationKey("TST-UNKNOWN"))
+}
+
+func Test_Catalog_Export(t *testing.T) {
+	withTestCatalog(t)
+	Register(CatalogEntry{Code: "TST-CAT-005", Message: "a|b", GrpcStatus: St(5)})
+
+	js := &bytes.Buffer{}
+	assert.Nil(t, ExportJson(js))
+	var entries []CatalogEntry
+	assert.Nil(t, json.Unmarshal(js.Bytes(), &entries))
+	assert.NotEmpty(t, entries)
+
+	md := &bytes.Buffer{}
+	assert.Nil(t, ExportMarkdown(md))
+	assert.True(t, strings.Contains(md.String(), "|TST-CAT-005||5||errors.app.code.tst.cat.005|a\\|b|"))
+}
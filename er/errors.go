@@ -6,6 +6,7 @@ import (
 	kitContext "github.com/exluap/kit/context"
 	"github.com/pkg/errors"
 	"reflect"
+	"time"
 )
 
 // FF specifies list of fields
@@ -18,6 +19,9 @@ type AppError struct {
 	httpStatus *uint32
 	code       string
 	fields     FF
+	retryable  bool
+	retryAfter time.Duration
+	severity   Severity
 }
 
 // AppErrBuilder allows building AppError object
@@ -33,6 +37,13 @@ type AppErrBuilder interface {
 	// HttpSt attaches HTTP status
 	// it gives some hint to API gateway layer what HTTP status to return client
 	HttpSt(status uint32) AppErrBuilder
+	// Retryable marks error as transient, so callers may retry the operation
+	Retryable() AppErrBuilder
+	// RetryAfter marks error as transient and attaches a hint when the operation can be retried
+	RetryAfter(d time.Duration) AppErrBuilder
+	// Severity attaches severity
+	// it allows choosing log level, so expected business errors don't look like real faults
+	Severity(s Severity) AppErrBuilder
 	// Err builds error with all specified attributes
 	Err() error
 }
@@ -79,6 +90,22 @@ func (b *appErrBuildImpl) HttpSt(status uint32) AppErrBuilder {
 	return b
 }
 
+func (b *appErrBuildImpl) Retryable() AppErrBuilder {
+	b.appErr.retryable = true
+	return b
+}
+
+func (b *appErrBuildImpl) RetryAfter(d time.Duration) AppErrBuilder {
+	b.appErr.retryable = true
+	b.appErr.retryAfter = d
+	return b
+}
+
+func (b *appErrBuildImpl) Severity(s Severity) AppErrBuilder {
+	b.appErr.severity = s
+	return b
+}
+
 func (b *appErrBuildImpl) Err() error {
 	return b.appErr
 }
@@ -101,8 +128,14 @@ func WrapWithBuilder(cause error, code string, format string, args ...interface{
 
 // newAppErr creates a new AppError
 func newAppErr(code string, format string, args ...interface{}) *AppError {
+	var err error
+	if StackCaptureEnabled() {
+		err = errors.Errorf(format, args...)
+	} else {
+		err = fmt.Errorf(format, args...)
+	}
 	return &AppError{
-		error:  errors.Errorf(format, args...),
+		error:  err,
 		code:   code,
 		fields: make(FF),
 	}
@@ -157,24 +190,114 @@ func (e *AppError) HttpStatus() *uint32 {
 	return e.httpStatus
 }
 
+// Retryable indicates if error is transient and operation can be retried
+func (e *AppError) Retryable() bool {
+	return e.retryable
+}
+
+// RetryAfter returns a hint when the operation can be retried (zero if not specified)
+func (e *AppError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// Severity returns severity of error
+// if it isn't specified explicitly, errors with 4xx HTTP status are considered as warning, others as critical
+func (e *AppError) Severity() Severity {
+	if e.severity != "" {
+		return e.severity
+	}
+	if e.httpStatus != nil && *e.httpStatus >= 400 && *e.httpStatus < 500 {
+		return SeverityWarning
+	}
+	return SeverityCritical
+}
+
 // Wrap wraps error to a AppError object
 func Wrap(cause error, code string, format string, args ...interface{}) error {
 	return wrap(cause, code, format, args...)
 }
 
 func wrap(cause error, code string, format string, args ...interface{}) *AppError {
+	var err error
+	if StackCaptureEnabled() {
+		err = errors.Wrapf(cause, format, args...)
+	} else {
+		err = errors.WithMessagef(cause, format, args...)
+	}
 	return &AppError{
-		error:  errors.Wrapf(cause, format, args...),
+		error:  err,
 		code:   code,
 		fields: make(FF),
 	}
 }
 
+// Unwrap returns the underlying error, so AppError participates in standard error chains
+func (e *AppError) Unwrap() error {
+	return e.error
+}
+
+// Is reports whether target is *AppError with the same code
+// it allows using errors.Is(err, er.New(code, "")) to check for a code anywhere in the chain
+func (e *AppError) Is(target error) bool {
+	if t, ok := target.(*AppError); ok {
+		return t.code == e.code
+	}
+	return false
+}
+
 // Is checks if error interface is asserted to *AppError
-// if true, it returns *AppError
+// it walks through the error chain, so errors wrapped with fmt.Errorf("%w") are recognised as well
+// if true, it returns the first *AppError found in the chain
 func Is(e error) (*AppError, bool) {
-	appErr, ok := e.(*AppError)
-	return appErr, ok
+	var appErr *AppError
+	if errors.As(e, &appErr) {
+		return appErr, true
+	}
+	return nil, false
+}
+
+// As walks through the error chain and returns the first *AppError having the given code
+// errors aggregated by *MultiError are checked as well
+func As(e error, code string) (*AppError, bool) {
+	for e != nil {
+		if appErr, ok := e.(*AppError); ok && appErr.code == code {
+			return appErr, true
+		}
+		if multi, ok := e.(*MultiError); ok {
+			for _, item := range multi.errs {
+				if appErr, ok := As(item, code); ok {
+					return appErr, true
+				}
+			}
+		}
+		e = errors.Unwrap(e)
+	}
+	return nil, false
+}
+
+// IsRetryable checks if there is retryable *AppError in the error chain
+// *MultiError is retryable only if all the aggregated errors are retryable
+func IsRetryable(e error) bool {
+	for e != nil {
+		if appErr, ok := e.(*AppError); ok && appErr.retryable {
+			return true
+		}
+		if multi, ok := e.(*MultiError); ok {
+			return multi.Retryable()
+		}
+		e = errors.Unwrap(e)
+	}
+	return false
+}
+
+// HasCode checks if there is *AppError with the given code in the error chain
+// code of *MultiError and codes of the aggregated errors are checked as well
+func HasCode(e error, code string) bool {
+	if _, ok := As(e, code); ok {
+		return true
+	}
+	multi, ok := IsMulti(e)
+	return ok && multi.code == code
 }
 
 type withStackAppErr struct {
@@ -184,3 +307,7 @@ type withStackAppErr struct {
 func (s *withStackAppErr) Error() string {
 	return s.AppError.WithStack()
 }
+
+func (s *withStackAppErr) Unwrap() error {
+	return s.AppError
+}
@@ -2,10 +2,12 @@ package er
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	kitContext "github.com/exluap/kit/context"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 // by default prints in format "code: message"
@@ -100,3 +102,88 @@ func Test_NewWithBuilder_WhenFields(t *testing.T) {
 	}
 	t.Fatal()
 }
+
+func Test_Is_WhenWrappedWithFmt(t *testing.T) {
+	e := fmt.Errorf("outer: %w", New("ERR-123", "%s happened", "shit"))
+	appErr, ok := Is(e)
+	assert.True(t, ok)
+	assert.Equal(t, "ERR-123", appErr.Code())
+}
+
+func Test_Is_WhenWithStackErr(t *testing.T) {
+	e := New("ERR-123", "%s happened", "shit")
+	appErr, _ := Is(e)
+	res, ok := Is(appErr.WithStackErr())
+	assert.True(t, ok)
+	assert.Equal(t, "ERR-123", res.Code())
+}
+
+func Test_Is_WhenNotAppErr(t *testing.T) {
+	_, ok := Is(fmt.Errorf("original issue"))
+	assert.False(t, ok)
+	_, ok = Is(nil)
+	assert.False(t, ok)
+}
+
+func Test_HasCode_WhenTwoWrappers(t *testing.T) {
+	originalErr := fmt.Errorf("original issue")
+	e := Wrap(originalErr, "ERR-123", "%s happened", "shit")
+	e2 := fmt.Errorf("wrapped: %w", Wrap(e, "ERR-124", "very bad %s happened", "shit"))
+	assert.True(t, HasCode(e2, "ERR-123"))
+	assert.True(t, HasCode(e2, "ERR-124"))
+	assert.False(t, HasCode(e2, "ERR-125"))
+	appErr, ok := As(e2, "ERR-123")
+	assert.True(t, ok)
+	assert.Equal(t, "shit happened: original issue", appErr.Message())
+	assert.True(t, errors.Is(e2, originalErr))
+}
+
+func Test_ErrorsIs_ByCode(t *testing.T) {
+	e := fmt.Errorf("wrapped: %w", New("ERR-123", "%s happened", "shit"))
+	assert.True(t, errors.Is(e, New("ERR-123", "")))
+	assert.False(t, errors.Is(e, New("ERR-124", "")))
+}
+
+func Test_NewWithBuilder_WhenRetryable(t *testing.T) {
+	e := WithBuilder("ERR-123", "%s happens", "shit").Retryable().Err()
+	appErr, _ := Is(e)
+	assert.True(t, appErr.Retryable())
+	assert.Equal(t, time.Duration(0), appErr.RetryAfter())
+
+	e = WithBuilder("ERR-123", "%s happens", "shit").RetryAfter(time.Second).Err()
+	appErr, _ = Is(e)
+	assert.True(t, appErr.Retryable())
+	assert.Equal(t, time.Second, appErr.RetryAfter())
+
+	assert.False(t, IsRetryable(New("ERR-123", "%s happens", "shit")))
+}
+
+func Test_IsRetryable_WhenWrapped(t *testing.T) {
+	cause := WithBuilder("ERR-123", "%s happens", "shit").Retryable().Err()
+	e := fmt.Errorf("outer: %w", Wrap(cause, "ERR-124", "wrapped"))
+	assert.True(t, IsRetryable(e))
+	assert.False(t, IsRetryable(fmt.Errorf("original issue")))
+}
+
+func Test_Severity(t *testing.T) {
+	appErr, _ := Is(New("ERR-123", "%s happens", "shit"))
+	assert.Equal(t, SeverityCritical, appErr.Severity())
+
+	appErr, _ = Is(WithBuilder("ERR-123", "not found").HttpSt(404).Err())
+	assert.Equal(t, SeverityWarning, appErr.Severity())
+
+	appErr, _ = Is(WithBuilder("ERR-123", "not found").HttpSt(404).Severity(SeverityBusiness).Err())
+	assert.Equal(t, SeverityBusiness, appErr.Severity())
+
+	assert.Equal(t, SeverityCritical, SeverityOf(fmt.Errorf("original issue")))
+	assert.Equal(t, SeverityBusiness, SeverityOf(fmt.Errorf("wrapped: %w", appErr)))
+}
+
+func Test_Severity_WhenMulti(t *testing.T) {
+	m := NewMulti("ERR-200", "validation failed").
+		Add(WithBuilder("ERR-201", "a").Severity(SeverityBusiness).Err()).
+		Add(WithBuilder("ERR-202", "b").HttpSt(400).Err())
+	assert.Equal(t, SeverityWarning, SeverityOf(m))
+	assert.Equal(t, SeverityWarning, m.Severity())
+	assert.Equal(t, SeverityCritical, SeverityOf(NewMulti("ERR-200", "empty")))
+}
@@ -0,0 +1,198 @@
+package er
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"strings"
+	"time"
+)
+
+// MultiError aggregates multiple AppError objects (validation, batch processing etc.)
+// it has its own code and message describing the whole failure
+type MultiError struct {
+	code       string
+	message    string
+	grpcStatus *uint32
+	httpStatus *uint32
+	errs       []*AppError
+}
+
+// NewMulti creates a new empty MultiError
+func NewMulti(code string, format string, args ...interface{}) *MultiError {
+	return &MultiError{
+		code:    code,
+		message: fmt.Sprintf(format, args...),
+	}
+}
+
+// Add adds errors to the aggregate
+// nil errors are skipped, errors which aren't AppError are wrapped with the code of the aggregate
+// if MultiError is passed, its items are added
+func (m *MultiError) Add(errs ...error) *MultiError {
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		if multi, ok := IsMulti(err); ok {
+			m.errs = append(m.errs, multi.errs...)
+			continue
+		}
+		if appErr, ok := Is(err); ok {
+			m.errs = append(m.errs, appErr)
+		} else {
+			m.errs = append(m.errs, fromError(err, m.code))
+		}
+	}
+	return m
+}
+
+// fromError converts error to AppError with the given code
+// the error is kept as a cause and its message is used as is
+func fromError(cause error, code string) *AppError {
+	err := cause
+	if StackCaptureEnabled() {
+		err = errors.WithStack(cause)
+	}
+	return &AppError{
+		error:  err,
+		code:   code,
+		fields: make(FF),
+	}
+}
+
+// GrpcSt attaches gRPC status
+func (m *MultiError) GrpcSt(status uint32) *MultiError {
+	m.grpcStatus = &status
+	return m
+}
+
+// HttpSt attaches HTTP status
+func (m *MultiError) HttpSt(status uint32) *MultiError {
+	m.httpStatus = &status
+	return m
+}
+
+// ErrOrNil returns nil if there are no errors aggregated, otherwise returns the aggregate itself
+func (m *MultiError) ErrOrNil() error {
+	if m == nil || len(m.errs) == 0 {
+		return nil
+	}
+	return m
+}
+
+// Errors returns list of aggregated errors
+func (m *MultiError) Errors() []*AppError {
+	return m.errs
+}
+
+// Len returns number of aggregated errors
+func (m *MultiError) Len() int {
+	return len(m.errs)
+}
+
+// Code returns error code of the aggregate
+func (m *MultiError) Code() string {
+	return m.code
+}
+
+// Message returns message of the aggregate
+func (m *MultiError) Message() string {
+	return m.message
+}
+
+// Error returns combined message of all aggregated errors
+func (m *MultiError) Error() string {
+	items := make([]string, 0, len(m.errs))
+	for _, e := range m.errs {
+		items = append(items, e.Error())
+	}
+	return fmt.Sprintf("%s: %s [%s]", m.code, m.message, strings.Join(items, "; "))
+}
+
+// GrpcStatus returns gRPC status
+// if it isn't set explicitly and all the aggregated errors have the same status, this status is returned
+func (m *MultiError) GrpcStatus() *uint32 {
+	if m.grpcStatus != nil {
+		return m.grpcStatus
+	}
+	return commonStatus(m.errs, (*AppError).GrpcStatus)
+}
+
+// HttpStatus returns HTTP status
+// if it isn't set explicitly and all the aggregated errors have the same status, this status is returned
+func (m *MultiError) HttpStatus() *uint32 {
+	if m.httpStatus != nil {
+		return m.httpStatus
+	}
+	return commonStatus(m.errs, (*AppError).HttpStatus)
+}
+
+// Retryable checks if the whole operation can be retried, it's true only if all the aggregated errors are retryable
+func (m *MultiError) Retryable() bool {
+	for _, e := range m.errs {
+		if !e.Retryable() {
+			return false
+		}
+	}
+	return len(m.errs) > 0
+}
+
+// RetryAfter returns the longest retry hint of the aggregated errors
+func (m *MultiError) RetryAfter() time.Duration {
+	var res time.Duration
+	for _, e := range m.errs {
+		if e.RetryAfter() > res {
+			res = e.RetryAfter()
+		}
+	}
+	return res
+}
+
+// Severity returns the highest severity of the aggregated errors, critical if there are no errors
+func (m *MultiError) Severity() Severity {
+	var res Severity
+	for _, e := range m.errs {
+		if s := e.Severity(); severityWeights[s] > severityWeights[res] {
+			res = s
+		}
+	}
+	if res == "" {
+		res = SeverityCritical
+	}
+	return res
+}
+
+// Is reports whether target is *AppError with the code of the aggregate or of any aggregated error
+// it allows using errors.Is(err, er.New(code, "")) for multi errors
+func (m *MultiError) Is(target error) bool {
+	if t, ok := target.(*AppError); ok && t.code == m.code {
+		return true
+	}
+	for _, e := range m.errs {
+		if errors.Is(e, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func commonStatus(errs []*AppError, stFn func(*AppError) *uint32) *uint32 {
+	var res *uint32
+	for _, e := range errs {
+		st := stFn(e)
+		if st == nil || (res != nil && *res != *st) {
+			return nil
+		}
+		res = st
+	}
+	return res
+}
+
+// IsMulti checks if there is *MultiError in the error chain
+func IsMulti(e error) (*MultiError, bool) {
+	var multi *MultiError
+	if errors.As(e, &multi) {
+		return multi, true
+	}
+	return nil, false
+}
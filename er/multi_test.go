@@ -0,0 +1,76 @@
+package er
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_Multi_WhenEmpty(t *testing.T) {
+	m := NewMulti("ERR-200", "validation failed")
+	assert.Nil(t, m.ErrOrNil())
+	assert.Nil(t, m.Add(nil).ErrOrNil())
+}
+
+func Test_Multi_Add(t *testing.T) {
+	m := NewMulti("ERR-200", "validation failed").
+		Add(New("ERR-201", "field %s is empty", "a")).
+		Add(fmt.Errorf("original issue")).
+		Add(NewMulti("ERR-300", "nested").Add(New("ERR-301", "nested issue")))
+	assert.Equal(t, 3, m.Len())
+	assert.Equal(t, "ERR-201", m.Errors()[0].Code())
+	assert.Equal(t, "ERR-200", m.Errors()[1].Code())
+	assert.Equal(t, "ERR-301", m.Errors()[2].Code())
+	fmt.Println(m.ErrOrNil())
+
+	res, ok := IsMulti(fmt.Errorf("wrapped: %w", m))
+	assert.True(t, ok)
+	assert.Equal(t, "validation failed", res.Message())
+}
+
+func Test_Multi_HttpStatus(t *testing.T) {
+	m := NewMulti("ERR-200", "validation failed").
+		Add(WithBuilder("ERR-201", "a").HttpSt(400).Err()).
+		Add(WithBuilder("ERR-202", "b").HttpSt(400).Err())
+	assert.Equal(t, uint32(400), *m.HttpStatus())
+
+	m.Add(WithBuilder("ERR-203", "c").HttpSt(404).Err())
+	assert.Nil(t, m.HttpStatus())
+
+	m.HttpSt(422)
+	assert.Equal(t, uint32(422), *m.HttpStatus())
+}
+
+func Test_Multi_WrapsNonAppError(t *testing.T) {
+	cause := fmt.Errorf("original issue")
+	m := NewMulti("ERR-200", "batch failed").Add(cause)
+	assert.Equal(t, "original issue", m.Errors()[0].Message())
+	assert.True(t, errors.Is(m.Errors()[0], cause))
+}
+
+func Test_Multi_HasCode(t *testing.T) {
+	m := NewMulti("ERR-200", "validation failed").Add(New("ERR-201", "a"), New("ERR-202", "b"))
+	err := fmt.Errorf("wrapped: %w", m.ErrOrNil())
+	assert.True(t, HasCode(err, "ERR-200"))
+	assert.True(t, HasCode(err, "ERR-202"))
+	assert.False(t, HasCode(err, "ERR-203"))
+	appErr, ok := As(err, "ERR-201")
+	assert.True(t, ok)
+	assert.Equal(t, "a", appErr.Message())
+	assert.True(t, errors.Is(err, New("ERR-201", "")))
+	assert.True(t, errors.Is(err, New("ERR-200", "")))
+}
+
+func Test_Multi_Retryable(t *testing.T) {
+	m := NewMulti("ERR-200", "batch failed").
+		Add(WithBuilder("ERR-201", "a").RetryAfter(time.Second).Err()).
+		Add(WithBuilder("ERR-202", "b").RetryAfter(time.Second * 2).Err())
+	assert.True(t, IsRetryable(m))
+	assert.Equal(t, time.Second*2, m.RetryAfter())
+
+	m.Add(New("ERR-203", "c"))
+	assert.False(t, IsRetryable(m))
+	assert.False(t, IsRetryable(NewMulti("ERR-200", "empty")))
+}
@@ -0,0 +1,29 @@
+package er
+
+// Severity specifies how serious error is
+type Severity string
+
+const (
+	SeverityBusiness = Severity("business") // SeverityBusiness - expected error of business logic, normally logged with info level
+	SeverityWarning  = Severity("warning")  // SeverityWarning - error deserves attention, but isn't a fault (invalid request etc.)
+	SeverityCritical = Severity("critical") // SeverityCritical - real fault, logged with error level
+)
+
+var severityWeights = map[Severity]int{
+	SeverityBusiness: 1,
+	SeverityWarning:  2,
+	SeverityCritical: 3,
+}
+
+// SeverityOf returns severity of error
+// for MultiError it's the highest severity of aggregated errors
+// errors which aren't AppError are considered as critical
+func SeverityOf(e error) Severity {
+	if multi, ok := IsMulti(e); ok {
+		return multi.Severity()
+	}
+	if appErr, ok := Is(e); ok {
+		return appErr.Severity()
+	}
+	return SeverityCritical
+}
@@ -0,0 +1,109 @@
+package er
+
+import (
+	"github.com/pkg/errors"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Frame is a structured stack frame
+type Frame struct {
+	Function string `json:"func"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+const maxStackDepth = 32
+
+var (
+	// stackCaptureDisabled if not zero, stack isn't captured when AppError is created
+	stackCaptureDisabled int32
+	// trimPrefixesMu guards trimPrefixes
+	trimPrefixesMu sync.RWMutex
+	// trimPrefixes - frames of functions with these prefixes are trimmed from the top of stack
+	// kit internals (error factories of kit packages, helpers) are trimmed by default
+	trimPrefixes = []string{"github.com/exluap/kit/"}
+)
+
+// SetStackCapture enables or disables stack capturing when AppError is created
+// disable it for hot paths where stack isn't needed, it's enabled by default
+func SetStackCapture(enabled bool) {
+	if enabled {
+		atomic.StoreInt32(&stackCaptureDisabled, 0)
+	} else {
+		atomic.StoreInt32(&stackCaptureDisabled, 1)
+	}
+}
+
+// StackCaptureEnabled indicates if stack is captured when AppError is created
+func StackCaptureEnabled() bool {
+	return atomic.LoadInt32(&stackCaptureDisabled) == 0
+}
+
+// TrimStackFrames adds function prefixes (e.g. "github.com/acme/app/errors.") which are trimmed from the top of stack
+// it allows hiding error factories and helpers, so stack starts from the place where error actually happens
+func TrimStackFrames(prefixes ...string) {
+	trimPrefixesMu.Lock()
+	defer trimPrefixesMu.Unlock()
+	trimPrefixes = append(trimPrefixes, prefixes...)
+}
+
+// Stack returns structured frames of the current goroutine stack
+// skip is number of frames to skip, 0 identifies the caller of Stack
+func Stack(skip int) []Frame {
+	pcs := make([]uintptr, maxStackDepth)
+	n := runtime.Callers(skip+2, pcs)
+	return toFrames(pcs[:n])
+}
+
+// Frames returns structured stack frames of the place where error was created
+// if stack capture is disabled, it returns nil
+func (e *AppError) Frames() []Frame {
+	if st, ok := e.error.(interface{ StackTrace() errors.StackTrace }); ok {
+		trace := st.StackTrace()
+		pcs := make([]uintptr, len(trace))
+		for i, f := range trace {
+			pcs[i] = uintptr(f)
+		}
+		return toFrames(pcs)
+	}
+	return nil
+}
+
+func toFrames(pcs []uintptr) []Frame {
+
+	trimPrefixesMu.RLock()
+	prefixes := trimPrefixes
+	trimPrefixesMu.RUnlock()
+
+	res := make([]Frame, 0, len(pcs))
+	top := true
+	// CallersFrames expands inlined calls and takes care of return addresses
+	frames := runtime.CallersFrames(pcs)
+	for more := len(pcs) > 0; more; {
+		var f runtime.Frame
+		f, more = frames.Next()
+		// runtime frames aren't interesting
+		if f.Function == "" || strings.HasPrefix(f.Function, "runtime.") {
+			continue
+		}
+		// trim frames of kit internals from the top
+		if top && hasAnyPrefix(f.Function, prefixes) {
+			continue
+		}
+		top = false
+		res = append(res, Frame{Function: f.Function, File: f.File, Line: f.Line})
+	}
+	return res
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
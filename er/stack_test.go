@@ -0,0 +1,52 @@
+package er
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_Frames(t *testing.T) {
+	appErr, _ := Is(New("ERR-123", "%s happened", "shit"))
+	frames := appErr.Frames()
+	assert.NotEmpty(t, frames)
+	for _, f := range frames {
+		assert.False(t, strings.HasPrefix(f.Function, "runtime."))
+	}
+	// kit internals are trimmed, test function is within er package, so it's trimmed as well
+	assert.False(t, strings.HasPrefix(frames[0].Function, "github.com/exluap/kit/"))
+	assert.NotEmpty(t, frames[0].File)
+	assert.NotZero(t, frames[0].Line)
+}
+
+func Test_Frames_WhenCaptureDisabled(t *testing.T) {
+	SetStackCapture(false)
+	defer SetStackCapture(true)
+
+	appErr, _ := Is(New("ERR-123", "%s happened", "shit"))
+	assert.Empty(t, appErr.Frames())
+	assert.Equal(t, "ERR-123: shit happened", appErr.Error())
+
+	appErr, _ = Is(Wrap(appErr, "ERR-124", "wrapped"))
+	assert.Empty(t, appErr.Frames())
+	assert.Equal(t, "ERR-124: wrapped: ERR-123: shit happened", appErr.Error())
+	assert.True(t, HasCode(appErr, "ERR-123"))
+}
+
+func Test_Stack(t *testing.T) {
+	frames := Stack(0)
+	assert.NotEmpty(t, frames)
+	assert.False(t, strings.HasPrefix(frames[0].Function, "github.com/exluap/kit/"))
+}
+
+func Test_Frames_WhenTrimmedPrefix(t *testing.T) {
+	TrimStackFrames("testing.")
+	defer func() {
+		trimPrefixesMu.Lock()
+		trimPrefixes = trimPrefixes[:len(trimPrefixes)-1]
+		trimPrefixesMu.Unlock()
+	}()
+	// all the frames are either kit or testing ones
+	appErr, _ := Is(New("ERR-123", "%s happened", "shit"))
+	assert.Empty(t, appErr.Frames())
+}
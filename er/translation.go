@@ -0,0 +1,130 @@
+package er
+
+import (
+	"fmt"
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/en"
+	ut "github.com/go-playground/universal-translator"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// templateParam matches template params {0}, {1}..., other braces are kept as is
+var templateParam = regexp.MustCompile(`\{(\d+)\}`)
+
+// msgTemplate is a localized message template of error code
+type msgTemplate struct {
+	text string
+	// fields keeps names of fields substituted to template params {0}, {1}...
+	fields []string
+}
+
+// translations keeps localized message templates of error codes
+// universal translator is used to resolve locales, templates are substituted by the package itself
+type translations struct {
+	sync.RWMutex
+	uni *ut.UniversalTranslator
+	// templates per locale and code
+	templates map[string]map[string]*msgTemplate
+}
+
+var errTranslations = &translations{
+	uni:       ut.New(en.New(), en.New()),
+	templates: make(map[string]map[string]*msgTemplate),
+}
+
+// AddLocale adds a locale supported by translations
+// "en" locale is supported by default and is used as fallback
+// adding an already supported locale has no effect
+func AddLocale(l locales.Translator) error {
+	errTranslations.Lock()
+	defer errTranslations.Unlock()
+	if _, found := errTranslations.uni.GetTranslator(l.Locale()); found {
+		return nil
+	}
+	return errTranslations.uni.AddTranslator(l, false)
+}
+
+// AddTranslation registers a message template of error code for the locale
+// template params {0}, {1}... are substituted with values of the given fields of AppError, a param can be used several times
+// example: AddTranslation("en", "ERR-001", "field {0} is empty", "var")
+func AddTranslation(locale, code, template string, fields ...string) error {
+	if err := checkTemplateParams(template, len(fields)); err != nil {
+		return err
+	}
+
+	errTranslations.Lock()
+	defer errTranslations.Unlock()
+
+	trans, found := errTranslations.uni.GetTranslator(locale)
+	if !found {
+		return fmt.Errorf("locale %s isn't supported", locale)
+	}
+
+	lc := strings.ToLower(trans.Locale())
+	if _, ok := errTranslations.templates[lc]; !ok {
+		errTranslations.templates[lc] = make(map[string]*msgTemplate)
+	}
+	errTranslations.templates[lc][code] = &msgTemplate{text: template, fields: fields}
+	return nil
+}
+
+// checkTemplateParams checks that template uses params from {0} to {n-1}, each of them at least once
+func checkTemplateParams(template string, n int) error {
+	used := make(map[int]struct{})
+	for _, m := range templateParam.FindAllStringSubmatch(template, -1) {
+		i, _ := strconv.Atoi(m[1])
+		if i >= n {
+			return fmt.Errorf("template param {%d} has no field", i)
+		}
+		used[i] = struct{}{}
+	}
+	if len(used) != n {
+		return fmt.Errorf("template params don't match fields")
+	}
+	return nil
+}
+
+// Translate returns message of AppError translated to the first supported locale from the list
+// if none of locales is supported or there is no translation for the locale, fallback locale is used
+// if there is no translation for the code, it returns false
+func Translate(appErr *AppError, locales ...string) (string, bool) {
+	return TranslateCode(appErr.Code(), appErr.Fields(), locales...)
+}
+
+// TranslateCode returns translated message of error code with the given fields substituted
+func TranslateCode(code string, ff FF, locales ...string) (string, bool) {
+	errTranslations.RLock()
+	defer errTranslations.RUnlock()
+
+	trans, _ := errTranslations.uni.FindTranslator(locales...)
+	tmpl, ok := errTranslations.templates[strings.ToLower(trans.Locale())][code]
+	if !ok {
+		tmpl, ok = errTranslations.templates[strings.ToLower(errTranslations.uni.GetFallback().Locale())][code]
+		if !ok {
+			return "", false
+		}
+	}
+	return tmpl.substitute(ff), true
+}
+
+// substitute replaces template params with values of fields
+func (t *msgTemplate) substitute(ff FF) string {
+	return templateParam.ReplaceAllStringFunc(t.text, func(p string) string {
+		i, _ := strconv.Atoi(p[1 : len(p)-1])
+		if v, ok := ff[t.fields[i]]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+		return ""
+	})
+}
+
+// TranslateOrDefault returns translated message of AppError or its default message if there is no translation
+func TranslateOrDefault(appErr *AppError, locales ...string) string {
+	if msg, ok := Translate(appErr, locales...); ok {
+		return msg
+	}
+	return appErr.Message()
+}
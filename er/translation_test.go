@@ -0,0 +1,51 @@
+package er
+
+import (
+	"github.com/go-playground/locales/ru"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Translate(t *testing.T) {
+	assert.Nil(t, AddLocale(ru.New()))
+	assert.Nil(t, AddTranslation("en", "TST-TR-001", "field {0} of {1} is empty", "var", "obj"))
+	assert.Nil(t, AddTranslation("ru", "TST-TR-001", "поле {0} объекта {1} не заполнено", "var", "obj"))
+
+	appErr, _ := Is(WithBuilder("TST-TR-001", "empty").F(FF{"var": "name", "obj": "user"}).Err())
+
+	msg, ok := Translate(appErr, "ru_RU", "ru")
+	assert.True(t, ok)
+	assert.Equal(t, "поле name объекта user не заполнено", msg)
+
+	msg, ok = Translate(appErr, "de")
+	assert.True(t, ok)
+	assert.Equal(t, "field name of user is empty", msg)
+}
+
+func Test_Translate_WhenNoTranslation(t *testing.T) {
+	appErr, _ := Is(New("TST-TR-002", "default message"))
+	_, ok := Translate(appErr, "en")
+	assert.False(t, ok)
+	assert.Equal(t, "default message", TranslateOrDefault(appErr, "en"))
+}
+
+func Test_AddTranslation_WhenInvalid(t *testing.T) {
+	assert.Error(t, AddTranslation("en", "TST-TR-003", "field {0} is empty"))
+	assert.Error(t, AddTranslation("xx", "TST-TR-003", "field is empty"))
+	assert.Error(t, AddTranslation("en", "TST-TR-003", "field {1} is empty", "var"))
+}
+
+func Test_Translate_RepeatedParamsAndBraces(t *testing.T) {
+	assert.Nil(t, AddTranslation("en", "TST-TR-004", "{0} must differ from {1}, {0} is {not} allowed", "a", "b"))
+	msg, ok := TranslateCode("TST-TR-004", FF{"a": "x", "b": "y"}, "en")
+	assert.True(t, ok)
+	assert.Equal(t, "x must differ from y, x is {not} allowed", msg)
+}
+
+func Test_Translate_FallbackPerCode(t *testing.T) {
+	assert.Nil(t, AddLocale(ru.New()))
+	assert.Nil(t, AddTranslation("en", "TST-TR-005", "value {0} is invalid", "v"))
+	msg, ok := TranslateCode("TST-TR-005", FF{"v": 1}, "ru")
+	assert.True(t, ok)
+	assert.Equal(t, "value 1 is invalid", msg)
+}
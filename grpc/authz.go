@@ -0,0 +1,30 @@
+package grpc
+
+import (
+	"context"
+	"github.com/exluap/kit/auth"
+	"google.golang.org/grpc"
+)
+
+// UnaryServerAuthzInterceptor checks access to unary methods according to the policy
+// resource of the rule is full method name (e.g. /pkg.Service/Method)
+// roles are taken from the request context, so it must be chained after the interceptor restoring it from metadata
+// access denied AppError is returned the same way as handlers do, it's converted to PermissionDenied status by the status interceptor
+func UnaryServerAuthzInterceptor(policy *auth.Policy) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := policy.Check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerAuthzInterceptor checks access to stream methods according to the policy
+func StreamServerAuthzInterceptor(policy *auth.Policy) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := policy.Check(ss.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
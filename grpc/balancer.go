@@ -0,0 +1,176 @@
+package grpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/roundrobin"
+	_ "google.golang.org/grpc/health" // registers client side health checking
+	"google.golang.org/grpc/resolver"
+	"sync"
+)
+
+const (
+	StaticScheme = "kit-static"
+	// staticEndpoint is an endpoint of static targets, endpoints aren't part of the target, so it's stable when they change
+	staticEndpoint = "endpoints"
+	// BalancerRoundRobin spreads calls over all ready endpoints
+	BalancerRoundRobin = roundrobin.Name
+	// BalancerPickFirst sends all calls to the first ready endpoint
+	BalancerPickFirst = "pick_first"
+)
+
+// HealthCheckConfig enables client side health checking
+// endpoints are watched with grpc.health.v1 Watch and ejected from balancing while they aren't SERVING
+// it requires round_robin balancer
+type HealthCheckConfig struct {
+	// Service - name of the service checked, empty means the whole server
+	Service string
+}
+
+// StaticResolver resolves to a fixed list of endpoints
+// the list can be changed with UpdateEndpoints, connected clients are updated immediately, so it's handy in tests
+type StaticResolver struct {
+	sync.Mutex
+	endpoints []string
+	conns     map[*staticResolverConn]struct{}
+}
+
+func NewStaticResolver(endpoints ...string) *StaticResolver {
+	return &StaticResolver{endpoints: endpoints, conns: make(map[*staticResolverConn]struct{})}
+}
+
+// Target returns target name to dial with the resolver
+// it doesn't depend on endpoints, so it's stable when they are updated
+func (r *StaticResolver) Target() string {
+	return fmt.Sprintf("%s:///%s", StaticScheme, staticEndpoint)
+}
+
+// Endpoints returns actual endpoints
+func (r *StaticResolver) Endpoints() []string {
+	r.Lock()
+	defer r.Unlock()
+	return append([]string{}, r.endpoints...)
+}
+
+// UpdateEndpoints replaces endpoints and pushes them to all connected clients
+func (r *StaticResolver) UpdateEndpoints(endpoints ...string) {
+	r.Lock()
+	defer r.Unlock()
+	r.endpoints = endpoints
+	for c := range r.conns {
+		c.update(endpoints)
+	}
+}
+
+func (r *StaticResolver) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	r.Lock()
+	defer r.Unlock()
+	c := &staticResolverConn{r: r, cc: cc}
+	r.conns[c] = struct{}{}
+	c.update(r.endpoints)
+	return c, nil
+}
+
+func (r *StaticResolver) Scheme() string {
+	return StaticScheme
+}
+
+type staticResolverConn struct {
+	r  *StaticResolver
+	cc resolver.ClientConn
+}
+
+func (c *staticResolverConn) update(endpoints []string) {
+	addrs := make([]resolver.Address, 0, len(endpoints))
+	for _, e := range endpoints {
+		addrs = append(addrs, resolver.Address{Addr: e})
+	}
+	c.cc.UpdateState(resolver.State{Addresses: addrs})
+}
+
+func (c *staticResolverConn) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (c *staticResolverConn) Close() {
+	c.r.Lock()
+	defer c.r.Unlock()
+	delete(c.r.conns, c)
+}
+
+// dialTarget builds target and dial options which resolve and balance endpoints according to configuration
+// precedence: Resolver, Target, Endpoints, Host/Port
+// static targets have no server name, so TLS requires ServerName which is used as authority
+func dialTarget(cfg *ClientConfig) (string, []grpc.DialOption, error) {
+
+	var target string
+	var opts []grpc.DialOption
+	balancing := BalancerRoundRobin
+
+	static := func(r *StaticResolver) error {
+		target = r.Target()
+		opts = append(opts, grpc.WithResolvers(r))
+		if cfg.TLS != nil {
+			if cfg.TLS.ServerName == "" {
+				return ErrGrpcClientConfig(fmt.Errorf("TLS server name must be specified for multiple endpoints"))
+			}
+			opts = append(opts, grpc.WithAuthority(cfg.TLS.ServerName))
+		}
+		return nil
+	}
+
+	switch {
+	case cfg.Resolver != nil:
+		if err := static(cfg.Resolver); err != nil {
+			return "", nil, err
+		}
+	case cfg.Target != "":
+		target = cfg.Target
+	case len(cfg.Endpoints) > 0:
+		for _, e := range cfg.Endpoints {
+			if e == "" {
+				return "", nil, ErrGrpcClientConfig(fmt.Errorf("empty endpoint"))
+			}
+		}
+		if err := static(NewStaticResolver(cfg.Endpoints...)); err != nil {
+			return "", nil, err
+		}
+	default:
+		// single endpoint keeps grpc default behavior
+		target = fmt.Sprintf("%s:%s", cfg.Host, cfg.Port)
+		balancing = BalancerPickFirst
+	}
+
+	if cfg.Balancer != "" {
+		if balancer.Get(cfg.Balancer) == nil {
+			return "", nil, ErrGrpcClientConfig(fmt.Errorf("balancer %s isn't registered", cfg.Balancer))
+		}
+		balancing = cfg.Balancer
+	}
+	if cfg.HealthCheck != nil && balancing != BalancerRoundRobin {
+		return "", nil, ErrGrpcClientConfig(fmt.Errorf("health check requires %s balancer", BalancerRoundRobin))
+	}
+
+	sc, err := serviceConfig(balancing, cfg.HealthCheck)
+	if err != nil {
+		return "", nil, ErrGrpcClientConfig(err)
+	}
+	opts = append(opts, grpc.WithDefaultServiceConfig(sc))
+
+	return target, opts, nil
+}
+
+// serviceConfig builds gRPC service config JSON
+func serviceConfig(balancing string, hc *HealthCheckConfig) (string, error) {
+	sc := map[string]interface{}{
+		"loadBalancingConfig": []map[string]interface{}{{balancing: struct{}{}}},
+	}
+	if hc != nil {
+		sc["healthCheckConfig"] = map[string]interface{}{"serviceName": hc.Service}
+	}
+	b, err := json.Marshal(sc)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
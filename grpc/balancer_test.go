@@ -0,0 +1,125 @@
+package grpc
+
+import (
+	"context"
+	"github.com/exluap/kit/er"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testEndpoint struct {
+	addr   string
+	calls  int32
+	health *health.Server
+	stop   func()
+}
+
+func startTestEndpoint(t *testing.T) *testEndpoint {
+	e := &testEndpoint{health: health.NewServer()}
+	srv := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		atomic.AddInt32(&e.calls, 1)
+		return handler(ctx, req)
+	}))
+	grpc_health_v1.RegisterHealthServer(srv, e.health)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() { _ = srv.Serve(lis) }()
+	e.addr, e.stop = lis.Addr().String(), srv.Stop
+	return e
+}
+
+// callN makes n calls waiting for ready endpoints and resets counters before
+func callN(t *testing.T, cl *Client, n int, endpoints ...*testEndpoint) {
+	for _, e := range endpoints {
+		atomic.StoreInt32(&e.calls, 0)
+	}
+	hc := grpc_health_v1.NewHealthClient(cl.Conn)
+	for i := 0; i < n; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		_, err := hc.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "none"}, grpc.WaitForReady(true))
+		cancel()
+		// unknown service is answered with NotFound, the call reaches the endpoint anyway
+		assert.Error(t, err)
+	}
+}
+
+func Test_Balancer_RoundRobin(t *testing.T) {
+	e1, e2 := startTestEndpoint(t), startTestEndpoint(t)
+	defer e1.stop()
+	defer e2.stop()
+
+	cl, err := NewClient(&ClientConfig{Endpoints: []string{e1.addr, e2.addr}})
+	assert.Nil(t, err)
+	defer func() { _ = cl.Conn.Close() }()
+
+	// wait until both sub-connections are ready
+	assert.Eventually(t, func() bool {
+		callN(t, cl, 10, e1, e2)
+		return atomic.LoadInt32(&e1.calls) > 0 && atomic.LoadInt32(&e2.calls) > 0
+	}, time.Second*5, time.Millisecond*50)
+}
+
+func Test_Balancer_HealthCheckEjection(t *testing.T) {
+	e1, e2 := startTestEndpoint(t), startTestEndpoint(t)
+	defer e1.stop()
+	defer e2.stop()
+
+	r := NewStaticResolver(e1.addr, e2.addr)
+	cl, err := NewClient(&ClientConfig{Resolver: r, HealthCheck: &HealthCheckConfig{}})
+	assert.Nil(t, err)
+	defer func() { _ = cl.Conn.Close() }()
+
+	e2.health.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	assert.Eventually(t, func() bool {
+		callN(t, cl, 10, e1, e2)
+		return atomic.LoadInt32(&e1.calls) == 10 && atomic.LoadInt32(&e2.calls) == 0
+	}, time.Second*5, time.Millisecond*50)
+
+	// endpoint gets back when it's healthy again
+	e2.health.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	assert.Eventually(t, func() bool {
+		callN(t, cl, 10, e1, e2)
+		return atomic.LoadInt32(&e2.calls) > 0
+	}, time.Second*5, time.Millisecond*50)
+}
+
+func Test_Balancer_StaticResolverUpdate(t *testing.T) {
+	e1, e2 := startTestEndpoint(t), startTestEndpoint(t)
+	defer e1.stop()
+	defer e2.stop()
+
+	r := NewStaticResolver(e1.addr)
+	cl, err := NewClient(&ClientConfig{Resolver: r})
+	assert.Nil(t, err)
+	defer func() { _ = cl.Conn.Close() }()
+
+	callN(t, cl, 5, e1, e2)
+	assert.Equal(t, int32(5), atomic.LoadInt32(&e1.calls))
+
+	target := r.Target()
+	r.UpdateEndpoints(e2.addr)
+	// target doesn't depend on endpoints
+	assert.Equal(t, target, r.Target())
+	assert.Eventually(t, func() bool {
+		callN(t, cl, 5, e1, e2)
+		return atomic.LoadInt32(&e2.calls) == 5
+	}, time.Second*5, time.Millisecond*50)
+}
+
+func Test_Balancer_WhenInvalidConfig(t *testing.T) {
+	_, err := NewClient(&ClientConfig{Endpoints: []string{"127.0.0.1:1"}, Balancer: "unknown"})
+	assert.Error(t, err)
+	_, err = NewClient(&ClientConfig{Host: "127.0.0.1", Port: "1", HealthCheck: &HealthCheckConfig{}})
+	assert.Error(t, err)
+	_, err = NewClient(&ClientConfig{Endpoints: []string{""}})
+	assert.Error(t, err)
+	// TLS server name cannot be derived from multiple endpoints
+	_, err = NewClient(&ClientConfig{Endpoints: []string{"127.0.0.1:1", "127.0.0.1:2"}, TLS: &TLSConfig{}})
+	assert.True(t, er.HasCode(err, ErrCodeGrpcClientCfg))
+}
@@ -0,0 +1,159 @@
+package grpc
+
+import (
+	"context"
+	"github.com/exluap/kit/er"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+	"time"
+)
+
+const (
+	BreakerFailureThreshold = 5
+	BreakerOpenTimeout      = time.Second * 30
+)
+
+// BreakerConfig is circuit breaker configuration
+// breaker is kept per method, it opens after consecutive failures and lets a single probe call through after timeout
+type BreakerConfig struct {
+	// FailureThreshold - number of consecutive failures which opens breaker, 5 by default
+	FailureThreshold int `config:"failure-threshold"`
+	// OpenTimeout - how long breaker stays open before a probe call is allowed, 30s by default
+	OpenTimeout time.Duration `config:"open-timeout"`
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breaker is a circuit breaker of a single method
+type breaker struct {
+	sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+// breakers keeps circuit breakers per method
+type breakers struct {
+	sync.Mutex
+	cfg     *BreakerConfig
+	metrics *clientMetrics
+	items   map[string]*breaker
+	now     func() time.Time
+}
+
+func newBreakers(cfg *BreakerConfig, metrics *clientMetrics) *breakers {
+	c := *cfg
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = BreakerFailureThreshold
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = BreakerOpenTimeout
+	}
+	return &breakers{cfg: &c, metrics: metrics, items: make(map[string]*breaker), now: time.Now}
+}
+
+func (bs *breakers) get(method string) *breaker {
+	bs.Lock()
+	defer bs.Unlock()
+	b, ok := bs.items[method]
+	if !ok {
+		b = &breaker{}
+		bs.items[method] = b
+	}
+	return b
+}
+
+// allow checks if call can be made, if not, it returns time remaining until breaker lets a probe call through
+func (bs *breakers) allow(method string) (bool, time.Duration) {
+	b := bs.get(method)
+	b.Lock()
+	defer b.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if elapsed := bs.now().Sub(b.openedAt); elapsed < bs.cfg.OpenTimeout {
+			return false, bs.cfg.OpenTimeout - elapsed
+		}
+		// let a single probe call through
+		bs.setState(method, b, breakerHalfOpen)
+		return true, 0
+	case breakerHalfOpen:
+		// probe call is in progress
+		return false, bs.cfg.OpenTimeout
+	}
+	return true, 0
+}
+
+// done registers result of the call
+func (bs *breakers) done(method string, err error) {
+	b := bs.get(method)
+	b.Lock()
+	defer b.Unlock()
+	// call cancelled by the caller says nothing about the target, so it's neither success nor failure
+	// if it's a probe, breaker gets back to open state and lets the next probe through
+	if errCode(err) == codes.Canceled {
+		if b.state == breakerHalfOpen {
+			bs.setState(method, b, breakerOpen)
+		}
+		return
+	}
+	if !isBreakerFailure(err) {
+		b.failures = 0
+		bs.setState(method, b, breakerClosed)
+		return
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= bs.cfg.FailureThreshold {
+		b.openedAt = bs.now()
+		bs.setState(method, b, breakerOpen)
+	}
+}
+
+func (bs *breakers) setState(method string, b *breaker, state breakerState) {
+	b.state = state
+	bs.metrics.breakerState.WithLabelValues(method).Set(float64(state))
+}
+
+// isBreakerFailure checks if error means the target is unhealthy
+// business errors don't affect breaker
+func isBreakerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch errCode(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// unaryBreakerInterceptor short-circuits calls of methods with open breaker
+func (c *Client) unaryBreakerInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if ok, retryAfter := c.breakers.allow(method); !ok {
+			c.metrics.breakerRejected.WithLabelValues(method).Inc()
+			return ErrGrpcBreakerOpen(ctx, method, retryAfter)
+		}
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		c.breakers.done(method, err)
+		return err
+	}
+}
+
+// errCode takes gRPC code of error, AppError is checked for gRPC status
+func errCode(err error) codes.Code {
+	if appErr, ok := er.Is(err); ok {
+		if st := appErr.GrpcStatus(); st != nil {
+			return codes.Code(*st)
+		}
+		return codes.Unknown
+	}
+	return status.Code(err)
+}
@@ -0,0 +1,81 @@
+package grpc
+
+import (
+	"context"
+	"github.com/exluap/kit/er"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func Test_Breaker_States(t *testing.T) {
+	now := time.Now()
+	bs := newBreakers(&BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute}, newClientMetrics("test"))
+	bs.now = func() time.Time { return now }
+	unavailable := status.Error(codes.Unavailable, "unavailable")
+
+	// business errors don't open breaker
+	bs.done("m", status.Error(codes.NotFound, "not found"))
+	bs.done("m", status.Error(codes.NotFound, "not found"))
+	ok, _ := bs.allow("m")
+	assert.True(t, ok)
+
+	bs.done("m", unavailable)
+	bs.done("m", unavailable)
+	ok, retryAfter := bs.allow("m")
+	assert.False(t, ok)
+	assert.Equal(t, time.Minute, retryAfter)
+
+	// other methods aren't affected
+	ok, _ = bs.allow("other")
+	assert.True(t, ok)
+
+	// single probe is let through after timeout
+	now = now.Add(time.Minute)
+	ok, _ = bs.allow("m")
+	assert.True(t, ok)
+	ok, _ = bs.allow("m")
+	assert.False(t, ok)
+
+	// failed probe opens breaker again
+	bs.done("m", unavailable)
+	ok, _ = bs.allow("m")
+	assert.False(t, ok)
+
+	// cancelled probe doesn't close breaker, the next probe is let through
+	now = now.Add(time.Minute)
+	ok, _ = bs.allow("m")
+	assert.True(t, ok)
+	bs.done("m", status.Error(codes.Canceled, "cancelled"))
+	assert.Equal(t, breakerOpen, bs.get("m").state)
+	ok, _ = bs.allow("m")
+	assert.True(t, ok)
+	bs.done("m", unavailable)
+
+	// successful probe closes breaker
+	now = now.Add(time.Minute)
+	ok, _ = bs.allow("m")
+	assert.True(t, ok)
+	bs.done("m", nil)
+	ok, _ = bs.allow("m")
+	assert.True(t, ok)
+}
+
+func Test_Breaker_Interceptor(t *testing.T) {
+	c := &Client{metrics: newClientMetrics("test")}
+	c.breakers = newBreakers(&BreakerConfig{FailureThreshold: 1}, c.metrics)
+	calls := 0
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		return status.Error(codes.Unavailable, "unavailable")
+	}
+	interceptor := c.unaryBreakerInterceptor()
+	assert.Error(t, interceptor(context.Background(), "/test/Method", nil, nil, nil, invoker))
+	err := interceptor(context.Background(), "/test/Method", nil, nil, nil, invoker)
+	assert.True(t, er.HasCode(err, ErrCodeGrpcBreakerOpen))
+	assert.True(t, er.IsRetryable(err))
+	assert.Equal(t, 1, calls)
+}
@@ -2,32 +2,125 @@ package grpc
 
 import (
 	"context"
-	"fmt"
 	kitContext "github.com/exluap/kit/context"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	"io"
+	"strings"
+	"time"
 )
 
 // ClientConfig is gRPC client configuration
 type ClientConfig struct {
+	// Name - name of the client used as "target" label of client metrics
+	// if not specified, the target, host:port or the endpoint list is used
+	Name string `config:"name"`
 	Host string
 	Port string
+	// Endpoints - list of host:port, if specified, Host and Port are ignored and calls are balanced over endpoints
+	Endpoints []string
+	// Target - gRPC target name (e.g. dns:///svc:9000), DNS target is resolved to all addresses and calls are balanced over them
+	// if specified, Host, Port and Endpoints are ignored
+	Target string
+	// Resolver - static resolver which endpoints can be changed at runtime (e.g. in tests), if specified, Target, Host, Port and Endpoints are ignored
+	Resolver *StaticResolver
+	// Balancer - load balancing policy, round_robin by default if multiple endpoints can be resolved, otherwise pick_first
+	Balancer string
+	// HealthCheck - if specified, unhealthy endpoints are ejected from balancing
+	HealthCheck *HealthCheckConfig `config:"health-check"`
+	// Readiness - if specified, AwaitReadiness polls grpc.health.v1 of the remote server instead of watching connection state
+	Readiness *ReadinessConfig
+	// Timeout - default timeout of calls, it's applied only if the caller's context has no deadline
+	Timeout time.Duration
+	// TLS - if specified, connection is secured with TLS, otherwise it's insecure
+	TLS *TLSConfig
+	// UnaryInterceptors - custom unary interceptors executed after kit ones
+	UnaryInterceptors []grpc.UnaryClientInterceptor
+	// StreamInterceptors - custom stream interceptors executed after kit ones
+	StreamInterceptors []grpc.StreamClientInterceptor
+	// Retry - retry policy of unary calls, if not specified, calls aren't retried
+	Retry *RetryConfig
+	// Breaker - circuit breaker of unary calls, if not specified, breaker isn't used
+	Breaker *BreakerConfig
 }
 
 type Client struct {
 	*readinessAwaiter
-	Conn *grpc.ClientConn
+	Conn     *grpc.ClientConn
+	cfg      *ClientConfig
+	metrics  *clientMetrics
+	retry    *retryPolicy
+	breakers *breakers
+}
+
+// clientName identifies the client in metrics
+// static targets are the same for all the clients, so endpoints are used instead
+func clientName(cfg *ClientConfig, target string) string {
+	switch {
+	case cfg.Name != "":
+		return cfg.Name
+	case cfg.Resolver != nil:
+		return strings.Join(cfg.Resolver.Endpoints(), ",")
+	case cfg.Target == "" && len(cfg.Endpoints) > 0:
+		return strings.Join(cfg.Endpoints, ",")
+	}
+	return target
+}
+
+type detachedKey struct{}
+
+// Detach marks context, so gRPC calls made with it aren't cancelled with the caller's context
+// it's intended for fire-and-forget calls, request context is still propagated and the default timeout is still applied
+func Detach(ctx context.Context) context.Context {
+	return context.WithValue(ctx, detachedKey{}, true)
+}
+
+func isDetached(ctx context.Context) bool {
+	d, _ := ctx.Value(detachedKey{}).(bool)
+	return d
 }
 
 func NewClient(cfg *ClientConfig) (*Client, error) {
 
-	c := &Client{}
+	target, dialOpts, err := dialTarget(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{cfg: cfg, metrics: newClientMetrics(clientName(cfg, target))}
+
+	transportOpt := grpc.WithInsecure()
+	if cfg.TLS != nil {
+		creds, err := NewClientCredentials(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		transportOpt = grpc.WithTransportCredentials(creds)
+	}
 
-	gc, err := grpc.Dial(fmt.Sprintf("%s:%s", cfg.Host, cfg.Port),
-		grpc.WithInsecure(),
-		grpc.WithChainUnaryInterceptor(grpc_middleware.ChainUnaryClient(c.unaryClientInterceptor())))
-	grpc.WithChainStreamInterceptor(grpc_middleware.ChainStreamClient(c.streamClientInterceptor()))
+	// breaker is checked once per call, retries are made inside
+	unary := []grpc.UnaryClientInterceptor{c.unaryClientInterceptor()}
+	if cfg.Breaker != nil {
+		c.breakers = newBreakers(cfg.Breaker, c.metrics)
+		unary = append(unary, c.unaryBreakerInterceptor())
+	}
+	if cfg.Retry != nil && cfg.Retry.MaxAttempts > 1 {
+		policy, err := newRetryPolicy(cfg.Retry)
+		if err != nil {
+			return nil, err
+		}
+		c.retry = policy
+		unary = append(unary, c.unaryRetryInterceptor())
+	}
+	unary = append(unary, cfg.UnaryInterceptors...)
+	stream := append([]grpc.StreamClientInterceptor{c.streamClientInterceptor()}, cfg.StreamInterceptors...)
+
+	dialOpts = append(dialOpts,
+		transportOpt,
+		grpc.WithChainUnaryInterceptor(grpc_middleware.ChainUnaryClient(unary...)),
+		grpc.WithChainStreamInterceptor(grpc_middleware.ChainStreamClient(stream...)))
+
+	gc, err := grpc.Dial(target, dialOpts...)
 	if err != nil {
 		return nil, ErrGrpcClientDial(err)
 	}
@@ -38,14 +131,40 @@ func NewClient(cfg *ClientConfig) (*Client, error) {
 	return c, nil
 }
 
+// callCtx builds context of outgoing call
+// deadline and cancellation of the caller's context are kept unless the context is detached
+// request context is put to gRPC metadata
+func (c *Client) callCtx(parentCtx context.Context) (context.Context, context.CancelFunc) {
+
+	ctx := parentCtx
+	if isDetached(parentCtx) {
+		ctx = context.Background()
+	}
+
+	// keep metadata set by the caller, request context keys are overwritten, as the server takes the first value
+	md, _ := metadata.FromOutgoingContext(parentCtx)
+	if rqMd, ok := kitContext.FromContextToGrpcMD(parentCtx); ok {
+		md = md.Copy()
+		for k, v := range rqMd {
+			md.Set(k, v...)
+		}
+	}
+	if len(md) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, md)
+	}
+
+	if _, ok := ctx.Deadline(); !ok && c.cfg.Timeout > 0 {
+		return context.WithTimeout(ctx, c.cfg.Timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
 // this middleware is applied on client side
 // it retrieves session params from the context (normally it's populated in HTTP middleware or by another caller) and puts it to gRPS metadata
 func (c *Client) unaryClientInterceptor() grpc.UnaryClientInterceptor {
 	return func(parentCtx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-		ctx := context.Background()
-		if md, ok := kitContext.FromContextToGrpcMD(parentCtx); ok {
-			ctx = metadata.NewOutgoingContext(ctx, md)
-		}
+		ctx, cancel := c.callCtx(parentCtx)
+		defer cancel()
 		if err := invoker(ctx, method, req, reply, cc, opts...); err != nil {
 			return toAppError(err)
 		}
@@ -55,14 +174,68 @@ func (c *Client) unaryClientInterceptor() grpc.UnaryClientInterceptor {
 
 func (c *Client) streamClientInterceptor() grpc.StreamClientInterceptor {
 	return func(parentCtx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
-		ctx := context.Background()
-		if md, ok := kitContext.FromContextToGrpcMD(parentCtx); ok {
-			ctx = metadata.NewOutgoingContext(ctx, md)
-		}
+		ctx, cancel := c.callCtx(parentCtx)
 		clStream, err := streamer(ctx, desc, cc, method, opts...)
 		if err != nil {
-			err = toAppError(err)
+			cancel()
+			return nil, toAppError(err)
 		}
-		return clStream, err
+		return &clientStream{ClientStream: clStream, ctx: ctx, desc: desc, cancel: cancel}, nil
+	}
+}
+
+// clientStream converts errors of the stream to AppError and releases context of the stream when it's finished
+// the stream is finished when Header, RecvMsg or CloseSend fails or RecvMsg returns io.EOF
+// callers must either drain the stream or cancel its context, otherwise the stream is released only by timeout
+type clientStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	desc   *grpc.StreamDesc
+	cancel context.CancelFunc
+}
+
+func (s *clientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	if err != nil {
+		s.cancel()
+		return md, toAppError(err)
+	}
+	return md, nil
+}
+
+func (s *clientStream) CloseSend() error {
+	err := s.ClientStream.CloseSend()
+	if err != nil {
+		s.cancel()
+		if err != io.EOF {
+			return toAppError(err)
+		}
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil {
+		// stream is finished either with io.EOF or with error
+		s.cancel()
+		if err != io.EOF {
+			return toAppError(err)
+		}
+		return err
+	}
+	// if server doesn't stream, the stream is finished with the first successful message (e.g. CloseAndRecv)
+	if !s.desc.ServerStreams {
+		s.cancel()
+	}
+	return nil
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	// io.EOF means the stream is finished by the server, the actual status is returned by RecvMsg
+	if err != nil && err != io.EOF {
+		return toAppError(err)
 	}
+	return err
 }
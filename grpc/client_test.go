@@ -0,0 +1,189 @@
+package grpc
+
+import (
+	"context"
+	kitContext "github.com/exluap/kit/context"
+	"github.com/exluap/kit/er"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_CallCtx_KeepsDeadline(t *testing.T) {
+	c := &Client{cfg: &ClientConfig{Timeout: time.Hour}}
+	parent, parentCancel := context.WithTimeout(kitContext.NewRequestCtx().Test().ToContext(context.Background()), time.Minute)
+	parent = metadata.AppendToOutgoingContext(parent, "custom", "value", kitContext.GrpcMDKey, "stale")
+
+	ctx, cancel := c.callCtx(parent)
+	defer cancel()
+
+	parentDeadline, _ := parent.Deadline()
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.Equal(t, parentDeadline, deadline)
+
+	md, _ := metadata.FromOutgoingContext(ctx)
+	assert.Equal(t, []string{"value"}, md.Get("custom"))
+	// request context of the caller's metadata is replaced
+	assert.Len(t, md.Get(kitContext.GrpcMDKey), 1)
+	assert.NotEqual(t, "stale", md.Get(kitContext.GrpcMDKey)[0])
+
+	parentCancel()
+	<-ctx.Done()
+}
+
+func Test_CallCtx_DefaultTimeout(t *testing.T) {
+	c := &Client{cfg: &ClientConfig{Timeout: time.Minute}}
+	ctx, cancel := c.callCtx(context.Background())
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+}
+
+func Test_CallCtx_WhenDetached(t *testing.T) {
+	c := &Client{cfg: &ClientConfig{Timeout: time.Minute}}
+	parent, parentCancel := context.WithCancel(kitContext.NewRequestCtx().Test().ToContext(context.Background()))
+	ctx, cancel := c.callCtx(Detach(parent))
+	defer cancel()
+
+	parentCancel()
+	assert.Nil(t, ctx.Err())
+	_, ok := ctx.Deadline()
+	assert.True(t, ok)
+	md, _ := metadata.FromOutgoingContext(ctx)
+	assert.NotEmpty(t, md.Get(kitContext.GrpcMDKey))
+}
+
+// startTestServer starts server with health service on a random port
+func startTestServer(t *testing.T, opts ...grpc.ServerOption) (string, func()) {
+	srv := grpc.NewServer(opts...)
+	grpc_health_v1.RegisterHealthServer(srv, health.NewServer())
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() { _ = srv.Serve(lis) }()
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+	return port, srv.Stop
+}
+
+func Test_Client_StreamErrors(t *testing.T) {
+	port, stop := startTestServer(t, grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return toGrpcStatus(er.WithBuilder("TST-STR-001", "stream failed").GrpcSt(uint32(codes.NotFound)).Err())
+	}))
+	defer stop()
+
+	var unaryCalls, streamCalls int32
+	cl, err := NewClient(&ClientConfig{
+		Host: "127.0.0.1",
+		Port: port,
+		UnaryInterceptors: []grpc.UnaryClientInterceptor{func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			atomic.AddInt32(&unaryCalls, 1)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}},
+		StreamInterceptors: []grpc.StreamClientInterceptor{func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			atomic.AddInt32(&streamCalls, 1)
+			// kit interceptor is executed first, so request context is already in metadata
+			md, _ := metadata.FromOutgoingContext(ctx)
+			assert.NotEmpty(t, md.Get(kitContext.GrpcMDKey))
+			return streamer(ctx, desc, cc, method, opts...)
+		}},
+	})
+	assert.Nil(t, err)
+	defer func() { _ = cl.Conn.Close() }()
+
+	ctx := kitContext.NewRequestCtx().Test().ToContext(context.Background())
+	hc := grpc_health_v1.NewHealthClient(cl.Conn)
+
+	_, err = hc.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&unaryCalls))
+
+	stream, err := hc.Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&streamCalls))
+	_, err = stream.Recv()
+	assert.True(t, er.HasCode(err, "TST-STR-001"))
+	appErr, _ := er.Is(err)
+	assert.Equal(t, uint32(codes.NotFound), *appErr.GrpcStatus())
+}
+
+func Test_Client_StreamReleasesContext(t *testing.T) {
+	// client-streaming handler receives all the messages and responds once
+	port, stop := startTestServer(t, grpc.UnknownServiceHandler(func(srv interface{}, ss grpc.ServerStream) error {
+		for {
+			if err := ss.RecvMsg(&grpc_health_v1.HealthCheckRequest{}); err != nil {
+				break
+			}
+		}
+		return ss.SendMsg(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING})
+	}))
+	defer stop()
+
+	cl, err := NewClient(&ClientConfig{Host: "127.0.0.1", Port: port})
+	assert.Nil(t, err)
+	defer func() { _ = cl.Conn.Close() }()
+
+	stream, err := cl.Conn.NewStream(context.Background(), &grpc.StreamDesc{ClientStreams: true}, "/kit.test.Collector/Collect")
+	assert.Nil(t, err)
+	assert.Nil(t, stream.SendMsg(&grpc_health_v1.HealthCheckRequest{}))
+	assert.Nil(t, stream.CloseSend())
+	rs := &grpc_health_v1.HealthCheckResponse{}
+	assert.Nil(t, stream.RecvMsg(rs))
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, rs.Status)
+	assert.Error(t, stream.(*clientStream).ctx.Err())
+}
+
+func Test_Client_AbandonedStreamReleased(t *testing.T) {
+	// server-streaming handler sends messages until the stream is cancelled by the client
+	finished := make(chan struct{}, 2)
+	port, stop := startTestServer(t, grpc.UnknownServiceHandler(func(srv interface{}, ss grpc.ServerStream) error {
+		defer func() { finished <- struct{}{} }()
+		for {
+			if err := ss.SendMsg(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}); err != nil {
+				return err
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+	}))
+	defer stop()
+
+	cl, err := NewClient(&ClientConfig{Host: "127.0.0.1", Port: port, Timeout: time.Millisecond * 200})
+	assert.Nil(t, err)
+	defer func() { _ = cl.Conn.Close() }()
+
+	open := func(ctx context.Context) *clientStream {
+		stream, err := cl.Conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, "/kit.test.Watcher/Watch")
+		assert.Nil(t, err)
+		assert.Nil(t, stream.CloseSend())
+		assert.Nil(t, stream.RecvMsg(&grpc_health_v1.HealthCheckResponse{}))
+		return stream.(*clientStream)
+	}
+
+	// abandoned stream is released when the caller cancels its context
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := open(ctx)
+	assert.Nil(t, stream.ctx.Err())
+	cancel()
+	assert.Error(t, stream.ctx.Err())
+	select {
+	case <-finished:
+	case <-time.After(time.Second * 5):
+		t.Fatal("stream isn't released")
+	}
+
+	// abandoned stream without the caller's deadline is released by the client timeout
+	stream = open(context.Background())
+	select {
+	case <-finished:
+	case <-time.After(time.Second * 5):
+		t.Fatal("stream isn't released")
+	}
+	assert.Error(t, stream.ctx.Err())
+}
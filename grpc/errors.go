@@ -3,6 +3,8 @@ package grpc
 import (
 	"context"
 	"github.com/exluap/kit/er"
+	"google.golang.org/grpc/codes"
+	"time"
 )
 
 const (
@@ -12,6 +14,13 @@ const (
 	ErrCodeGrpcSrvServe    = "GRPC-004"
 	ErrCodeGrpcSrvNotReady = "GRPC-005"
 	ErrCodeGrpcPanic       = "GRPC-006"
+	ErrCodeGrpcRequestCtx  = "GRPC-007"
+	ErrCodeGrpcTLS         = "GRPC-008"
+	ErrCodeGrpcBreakerOpen = "GRPC-009"
+	ErrCodeGrpcRetryConfig = "GRPC-010"
+	ErrCodeGrpcClientCfg   = "GRPC-011"
+	ErrCodeGrpcValidation  = "GRPC-012"
+	ErrCodeGrpcHealthDown  = "GRPC-013"
 )
 
 var (
@@ -28,4 +37,42 @@ var (
 	ErrGrpcPanicNoCtx = func(cause interface{}) error {
 		return er.WithBuilder(ErrCodeGrpcPanic, "panic").F(er.FF{"cause": cause}).Err()
 	}
+	ErrGrpcRequestCtx = func(cause error, method string) error {
+		return er.WrapWithBuilder(cause, ErrCodeGrpcRequestCtx, "invalid request context").F(er.FF{"method": method}).GrpcSt(uint32(codes.InvalidArgument)).Err()
+	}
+	ErrGrpcTLS         = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeGrpcTLS, "tls configuration").Err() }
+	ErrGrpcBreakerOpen = func(ctx context.Context, method string, retryAfter time.Duration) error {
+		return er.WithBuilder(ErrCodeGrpcBreakerOpen, "circuit breaker is open").F(er.FF{"method": method}).C(ctx).
+			GrpcSt(uint32(codes.Unavailable)).RetryAfter(retryAfter).Err()
+	}
+	ErrGrpcRetryConfig = func(cause error) error {
+		return er.WrapWithBuilder(cause, ErrCodeGrpcRetryConfig, "invalid retry configuration").Err()
+	}
+	ErrGrpcHealthShutdown = func(service string) error {
+		return er.WithBuilder(ErrCodeGrpcHealthDown, "health server is shut down").F(er.FF{"svc": service}).Err()
+	}
+	ErrGrpcClientConfig = func(cause error) error {
+		return er.WrapWithBuilder(cause, ErrCodeGrpcClientCfg, "invalid client configuration").Err()
+	}
+	ErrGrpcValidation = func(cause error, ctx context.Context, method string) error {
+		return er.WrapWithBuilder(cause, ErrCodeGrpcValidation, "invalid request").F(er.FF{"method": method}).C(ctx).GrpcSt(uint32(codes.InvalidArgument)).Err()
+	}
 )
+
+func init() {
+	er.Register(
+		er.CatalogEntry{Code: ErrCodeGrpcClientDial, Message: "grpc dial failed"},
+		er.CatalogEntry{Code: ErrCodeGrpCInvoke, Message: "grpc invoke failed"},
+		er.CatalogEntry{Code: ErrCodeGrpcSrvListen, Message: "grpc server listen failed"},
+		er.CatalogEntry{Code: ErrCodeGrpcSrvServe, Message: "grpc server serve failed"},
+		er.CatalogEntry{Code: ErrCodeGrpcSrvNotReady, Message: "service isn't ready within timeout"},
+		er.CatalogEntry{Code: ErrCodeGrpcPanic, Message: "panic", GrpcStatus: er.St(uint32(codes.Internal))},
+		er.CatalogEntry{Code: ErrCodeGrpcTLS, Message: "tls configuration"},
+		er.CatalogEntry{Code: ErrCodeGrpcBreakerOpen, Message: "circuit breaker is open", GrpcStatus: er.St(uint32(codes.Unavailable)), Retryable: true},
+		er.CatalogEntry{Code: ErrCodeGrpcRetryConfig, Message: "invalid retry configuration"},
+		er.CatalogEntry{Code: ErrCodeGrpcClientCfg, Message: "invalid client configuration"},
+		er.CatalogEntry{Code: ErrCodeGrpcValidation, Message: "invalid request", GrpcStatus: er.St(uint32(codes.InvalidArgument))},
+		er.CatalogEntry{Code: ErrCodeGrpcHealthDown, Message: "health server is shut down"},
+		er.CatalogEntry{Code: ErrCodeGrpcRequestCtx, Message: "invalid request context", GrpcStatus: er.St(uint32(codes.InvalidArgument))},
+	)
+}
@@ -0,0 +1,33 @@
+package gateway
+
+import (
+	"context"
+	"github.com/exluap/kit/er"
+	"net/http"
+)
+
+const (
+	ErrCodeGatewayCfg = "GW-001"
+	ErrCodeGatewayRq  = "GW-002"
+	ErrCodeGatewayRs  = "GW-003"
+)
+
+var (
+	ErrGatewayConfig = func(cause error, name string) error {
+		return er.WrapWithBuilder(cause, ErrCodeGatewayCfg, "invalid gateway configuration").F(er.FF{"name": name}).Err()
+	}
+	ErrGatewayRequest = func(cause error, ctx context.Context) error {
+		return er.WrapWithBuilder(cause, ErrCodeGatewayRq, "invalid gateway request").C(ctx).HttpSt(http.StatusBadRequest).Err()
+	}
+	ErrGatewayResponse = func(cause error, ctx context.Context, method string) error {
+		return er.WrapWithBuilder(cause, ErrCodeGatewayRs, "gateway response encoding").F(er.FF{"method": method}).C(ctx).HttpSt(http.StatusInternalServerError).Err()
+	}
+)
+
+func init() {
+	er.Register(
+		er.CatalogEntry{Code: ErrCodeGatewayCfg, Message: "invalid gateway configuration"},
+		er.CatalogEntry{Code: ErrCodeGatewayRq, Message: "invalid gateway request", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeGatewayRs, Message: "gateway response encoding", HttpStatus: er.St(http.StatusInternalServerError)},
+	)
+}
@@ -0,0 +1,411 @@
+package gateway
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/exluap/kit/er"
+	kitGrpc "github.com/exluap/kit/grpc"
+	kitHttp "github.com/exluap/kit/http"
+	"github.com/exluap/kit/log"
+	"github.com/gorilla/mux"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// errFieldNotFound is returned when the message has no field the value is mapped to
+var errFieldNotFound = errors.New("field not found")
+
+const (
+	// DefaultMaxBodyBytes - default max size of request body, it's the default max size of message received by gRPC server
+	DefaultMaxBodyBytes = 4 * 1024 * 1024
+)
+
+// Config is configuration of REST transcoding of gRPC service
+type Config struct {
+	// Prefix - path prefix of routes built by convention
+	Prefix string
+	// SkipUnannotated - if set, methods without google.api.http option aren't exposed
+	// otherwise they are exposed by convention as POST {prefix}/{package.Service}/{Method} with request message as body
+	SkipUnannotated bool `config:"skip-unannotated"`
+	// MaxBodyBytes - max size of request body, requests exceeding it are rejected with 413, 4MB by default
+	MaxBodyBytes int64 `config:"max-body-bytes"`
+}
+
+// gatewayRoute is a REST route of a gRPC method
+type gatewayRoute struct {
+	verb         string
+	path         string
+	body         string
+	responseBody string
+}
+
+// ServiceDescriptor finds a registered gRPC service descriptor by its full name (package.Service)
+// service is registered when its generated package is imported
+func ServiceDescriptor(fullName string) (protoreflect.ServiceDescriptor, error) {
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(fullName))
+	if err != nil {
+		return nil, ErrGatewayConfig(err, fullName)
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, ErrGatewayConfig(fmt.Errorf("%s isn't a service", fullName), fullName)
+	}
+	return sd, nil
+}
+
+// Register exposes unary methods of the gRPC service as JSON-over-HTTP routes on the root router of HTTP server
+// path and verb are taken from google.api.http method option or built by convention
+// calls are made with the kit client, so request context is propagated to gRPC metadata and errors are converted to AppError
+// errors are responded with RespondErrorWithRequest, HTTP status is derived from gRPC code if AppError has no HTTP status
+func Register(srv *kitHttp.Server, cl *kitGrpc.Client, sd protoreflect.ServiceDescriptor, cfg *Config, logger log.CLoggerFunc) error {
+
+	ctrl := &kitHttp.BaseController{Logger: logger, ErrorRenderer: srv.ErrorRenderer}
+	l := logger().Pr("grpc").Cmp("gateway").Mth("register").F(log.FF{"svc": sd.FullName()})
+
+	methods := sd.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		md := methods.Get(i)
+		if md.IsStreamingClient() || md.IsStreamingServer() {
+			continue
+		}
+		routes := methodRoutes(md, cfg)
+		for _, rt := range routes {
+			if rt.body != "" && rt.body != "*" && md.Input().Fields().ByName(protoreflect.Name(rt.body)) == nil {
+				return ErrGatewayConfig(fmt.Errorf("body field %s not found", rt.body), string(md.FullName()))
+			}
+			srv.RootRouter.Handle(rt.path, gatewayHandler(ctrl, cl, md, rt, maxBodyBytes(cfg))).Methods(rt.verb)
+			l.DbgF("%s %s -> %s", rt.verb, rt.path, md.FullName())
+		}
+	}
+	return nil
+}
+
+// methodRoutes builds routes of the method from google.api.http option including additional bindings
+func methodRoutes(md protoreflect.MethodDescriptor, cfg *Config) []*gatewayRoute {
+	rule, _ := proto.GetExtension(md.Options(), annotations.E_Http).(*annotations.HttpRule)
+	if rule == nil || rule.Pattern == nil {
+		if cfg.SkipUnannotated {
+			return nil
+		}
+		return []*gatewayRoute{{
+			verb: http.MethodPost,
+			path: fmt.Sprintf("%s/%s/%s", cfg.Prefix, md.Parent().FullName(), md.Name()),
+			body: "*",
+		}}
+	}
+	var res []*gatewayRoute
+	for _, r := range append([]*annotations.HttpRule{rule}, rule.AdditionalBindings...) {
+		rt := &gatewayRoute{body: r.Body, responseBody: r.ResponseBody}
+		switch p := r.Pattern.(type) {
+		case *annotations.HttpRule_Get:
+			rt.verb, rt.path = http.MethodGet, p.Get
+		case *annotations.HttpRule_Put:
+			rt.verb, rt.path = http.MethodPut, p.Put
+		case *annotations.HttpRule_Post:
+			rt.verb, rt.path = http.MethodPost, p.Post
+		case *annotations.HttpRule_Delete:
+			rt.verb, rt.path = http.MethodDelete, p.Delete
+		case *annotations.HttpRule_Patch:
+			rt.verb, rt.path = http.MethodPatch, p.Patch
+		case *annotations.HttpRule_Custom:
+			rt.verb, rt.path = p.Custom.Kind, p.Custom.Path
+		default:
+			continue
+		}
+		rt.path = muxPath(rt.path)
+		res = append(res, rt)
+	}
+	return res
+}
+
+var pathVarRegexp = regexp.MustCompile(`\{([^}=]+)(=([^}]*))?\}`)
+
+// muxPath converts path template (/v1/{name=items/*}) to mux path (/v1/{name:items/[^/]+})
+func muxPath(tpl string) string {
+	return pathVarRegexp.ReplaceAllStringFunc(tpl, func(v string) string {
+		m := pathVarRegexp.FindStringSubmatch(v)
+		if m[3] == "" || m[3] == "*" {
+			return "{" + m[1] + "}"
+		}
+		pattern := regexp.QuoteMeta(m[3])
+		pattern = strings.ReplaceAll(pattern, `\*\*`, `.+`)
+		pattern = strings.ReplaceAll(pattern, `\*`, `[^/]+`)
+		return "{" + m[1] + ":" + pattern + "}"
+	})
+}
+
+func maxBodyBytes(cfg *Config) int64 {
+	if cfg.MaxBodyBytes > 0 {
+		return cfg.MaxBodyBytes
+	}
+	return DefaultMaxBodyBytes
+}
+
+func gatewayHandler(ctrl *kitHttp.BaseController, cl *kitGrpc.Client, md protoreflect.MethodDescriptor, rt *gatewayRoute, limit int64) http.Handler {
+	method := fmt.Sprintf("/%s/%s", md.Parent().FullName(), md.Name())
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		ctx := r.Context()
+		rq := dynamicpb.NewMessage(md.Input())
+		if err := decodeGatewayRequest(ctx, r, rq, rt, limit); err != nil {
+			ctrl.RespondErrorWithRequest(w, r, err)
+			return
+		}
+
+		rs := dynamicpb.NewMessage(md.Output())
+		if err := cl.Conn.Invoke(ctx, method, rq, rs); err != nil {
+			ctrl.RespondErrorWithRequest(w, r, withHttpStatus(err))
+			return
+		}
+
+		var out proto.Message = rs
+		if rt.responseBody != "" {
+			if fd := md.Output().Fields().ByName(protoreflect.Name(rt.responseBody)); fd != nil && fd.Message() != nil {
+				out = rs.Get(fd).Message().Interface()
+			}
+		}
+		b, err := protojson.Marshal(out)
+		if err != nil {
+			ctrl.RespondErrorWithRequest(w, r, ErrGatewayResponse(err, ctx, method))
+			return
+		}
+		ctrl.RespondOK(w, json.RawMessage(b))
+	})
+}
+
+// decodeGatewayRequest populates request message from body, path variables and query parameters
+// body exceeding the limit is rejected, error of exceeded limit of HTTP server is returned as is
+func decodeGatewayRequest(ctx context.Context, r *http.Request, rq *dynamicpb.Message, rt *gatewayRoute, limit int64) error {
+
+	if rt.body != "" {
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, limit+1))
+		if err != nil {
+			if er.HasCode(err, kitHttp.ErrCodeHttpRequestTooLarge) {
+				return err
+			}
+			return ErrGatewayRequest(err, ctx)
+		}
+		if int64(len(body)) > limit {
+			return kitHttp.ErrHttpRequestTooLarge(ctx, limit)
+		}
+		if len(body) > 0 {
+			var target proto.Message = rq
+			if rt.body != "*" {
+				fd := rq.Descriptor().Fields().ByName(protoreflect.Name(rt.body))
+				target = rq.Mutable(fd).Message().Interface()
+			}
+			if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(body, target); err != nil {
+				return ErrGatewayRequest(err, ctx)
+			}
+		}
+	}
+
+	vars := mux.Vars(r)
+	for k, v := range vars {
+		if err := setField(rq, k, v); err != nil {
+			return ErrGatewayRequest(err, ctx)
+		}
+	}
+
+	// query parameters are mapped to fields not bound to the body or path
+	// parameters which aren't fields of the request (e.g. cache busters) are ignored
+	if rt.body != "*" {
+		for k, vv := range r.URL.Query() {
+			if _, ok := vars[k]; ok {
+				continue
+			}
+			for _, v := range vv {
+				if err := setField(rq, k, v); err != nil {
+					if errors.Is(err, errFieldNotFound) {
+						break
+					}
+					return ErrGatewayRequest(err, ctx)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// setField sets field of the message by dotted path (e.g. item.id), repeated fields are appended
+func setField(msg protoreflect.Message, path string, value string) error {
+	parts := strings.Split(path, ".")
+	for i, p := range parts {
+		fields := msg.Descriptor().Fields()
+		fd := fields.ByName(protoreflect.Name(p))
+		if fd == nil {
+			fd = fields.ByJSONName(p)
+		}
+		if fd == nil {
+			return fmt.Errorf("%s: %w", path, errFieldNotFound)
+		}
+		if i < len(parts)-1 {
+			if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+				return fmt.Errorf("field %s isn't a message", p)
+			}
+			msg = msg.Mutable(fd).Message()
+			continue
+		}
+		v, err := parseScalar(fd, value)
+		if err != nil {
+			return fmt.Errorf("field %s: %v", path, err)
+		}
+		if fd.IsList() {
+			msg.Mutable(fd).List().Append(v)
+		} else {
+			msg.Set(fd, v)
+		}
+	}
+	return nil
+}
+
+func parseScalar(fd protoreflect.FieldDescriptor, s string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(s), nil
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(s)
+		return protoreflect.ValueOfBool(v), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfInt32(int32(v)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(s, 10, 64)
+		return protoreflect.ValueOfInt64(v), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(s, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(v)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(s, 10, 64)
+		return protoreflect.ValueOfUint64(v), err
+	case protoreflect.FloatKind:
+		v, err := strconv.ParseFloat(s, 32)
+		return protoreflect.ValueOfFloat32(float32(v)), err
+	case protoreflect.DoubleKind:
+		v, err := strconv.ParseFloat(s, 64)
+		return protoreflect.ValueOfFloat64(v), err
+	case protoreflect.BytesKind:
+		v, err := base64.StdEncoding.DecodeString(s)
+		return protoreflect.ValueOfBytes(v), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		v, err := strconv.ParseInt(s, 10, 32)
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(v)), err
+	}
+	return protoreflect.Value{}, fmt.Errorf("kind %s isn't supported", fd.Kind())
+}
+
+// withHttpStatus sets HTTP status of AppError or MultiError derived from gRPC code if it isn't specified
+// a new AppError with the same code and message is built, the original error is kept as its cause
+// a new MultiError keeps the aggregated errors
+func withHttpStatus(err error) error {
+	if multi, ok := er.IsMulti(err); ok {
+		if multi.HttpStatus() != nil {
+			return err
+		}
+		items := make([]error, 0, multi.Len())
+		for _, e := range multi.Errors() {
+			items = append(items, e)
+		}
+		res := er.NewMulti(multi.Code(), "%s", multi.Message()).Add(items...).HttpSt(httpStatusOf(multi.Code(), multi.GrpcStatus()))
+		if multi.GrpcStatus() != nil {
+			res.GrpcSt(*multi.GrpcStatus())
+		}
+		return res
+	}
+	appErr, ok := er.Is(err)
+	if !ok || appErr.HttpStatus() != nil {
+		return err
+	}
+	httpStatus := httpStatusOf(appErr.Code(), appErr.GrpcStatus())
+	b := er.WithBuilder(appErr.Code(), "%s", appErr.Message()).F(appErr.Fields()).HttpSt(httpStatus)
+	if appErr.GrpcStatus() != nil {
+		b.GrpcSt(*appErr.GrpcStatus())
+	}
+	if appErr.Retryable() {
+		b.RetryAfter(appErr.RetryAfter())
+	}
+	// severity derived from the missing HTTP status is critical, it's derived from the new status instead
+	if sev := appErr.Severity(); sev != er.SeverityCritical || httpStatus >= http.StatusInternalServerError {
+		b.Severity(sev)
+	}
+	res, _ := er.Is(b.Err())
+	return &statusErr{AppError: res, cause: err}
+}
+
+// httpStatusOf takes HTTP status from the error catalog if registered, otherwise it's derived from gRPC code
+func httpStatusOf(code string, grpcStatus *uint32) uint32 {
+	if e, ok := er.Lookup(code); ok && e.HttpStatus != nil {
+		return *e.HttpStatus
+	}
+	if grpcStatus != nil {
+		return uint32(httpStatusFromCode(codes.Code(*grpcStatus)))
+	}
+	return http.StatusInternalServerError
+}
+
+// statusErr is AppError with HTTP status, which keeps the original error as its cause
+type statusErr struct {
+	*er.AppError
+	cause error
+}
+
+func (e *statusErr) Unwrap() error {
+	return e.cause
+}
+
+// As makes the error be taken as AppError with HTTP status rather than the original one
+func (e *statusErr) As(target interface{}) bool {
+	if t, ok := target.(**er.AppError); ok {
+		*t = e.AppError
+		return true
+	}
+	return false
+}
+
+// httpStatusFromCode maps gRPC code to HTTP status
+func httpStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.FailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
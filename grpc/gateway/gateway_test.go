@@ -0,0 +1,268 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/exluap/kit/er"
+	kitGrpc "github.com/exluap/kit/grpc"
+	kitHttp "github.com/exluap/kit/http"
+	"github.com/exluap/kit/log"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testLogger() log.CLoggerFunc {
+	return func() log.CLogger {
+		return log.L(log.Init(&log.Config{Level: log.TraceLevel}))
+	}
+}
+
+// testGatewayService builds descriptor of the service:
+//
+//	GetItem(ItemRq) returns (Item) - GET /v1/items/{id}
+//	CreateItem(Item) returns (Item) - POST /v1/items, body *
+//	Echo(Item) returns (Item) - no http option
+func testGatewayService(t *testing.T) protoreflect.ServiceDescriptor {
+	str, boolean := descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum()
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+	method := func(name, in string, rule *annotations.HttpRule) *descriptorpb.MethodDescriptorProto {
+		m := &descriptorpb.MethodDescriptorProto{Name: proto.String(name), InputType: proto.String(".kit.test." + in), OutputType: proto.String(".kit.test.Item")}
+		if rule != nil {
+			m.Options = &descriptorpb.MethodOptions{}
+			proto.SetExtension(m.Options, annotations.E_Http, rule)
+		}
+		return m
+	}
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("kit/test/gateway.proto"),
+		Package: proto.String("kit.test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("ItemRq"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("id"), JsonName: proto.String("id"), Number: proto.Int32(1), Type: str, Label: optional},
+				{Name: proto.String("verbose"), JsonName: proto.String("verbose"), Number: proto.Int32(2), Type: boolean, Label: optional},
+			}},
+			{Name: proto.String("Item"), Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("id"), JsonName: proto.String("id"), Number: proto.Int32(1), Type: str, Label: optional},
+				{Name: proto.String("name"), JsonName: proto.String("name"), Number: proto.Int32(2), Type: str, Label: optional},
+			}},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("ItemService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				method("GetItem", "ItemRq", &annotations.HttpRule{Pattern: &annotations.HttpRule_Get{Get: "/v1/items/{id}"}}),
+				method("CreateItem", "Item", &annotations.HttpRule{Pattern: &annotations.HttpRule_Post{Post: "/v1/items"}, Body: "*"}),
+				method("Echo", "Item", nil),
+			},
+		}},
+	}
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	assert.Nil(t, err)
+	return fd.Services().Get(0)
+}
+
+// startTestGatewayServer serves the service with dynamic messages
+func startTestGatewayServer(t *testing.T, sd protoreflect.ServiceDescriptor) (string, func()) {
+	item := sd.ParentFile().Messages().ByName("Item")
+	handler := func(in protoreflect.MessageDescriptor, fn func(rq *dynamicpb.Message) (*dynamicpb.Message, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+		return func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			rq := dynamicpb.NewMessage(in)
+			if err := dec(rq); err != nil {
+				return nil, err
+			}
+			return fn(rq)
+		}
+	}
+	field := func(m protoreflect.Message, name string) protoreflect.Value {
+		return m.Get(m.Descriptor().Fields().ByName(protoreflect.Name(name)))
+	}
+	newItem := func(id, name string) *dynamicpb.Message {
+		rs := dynamicpb.NewMessage(item)
+		rs.Set(item.Fields().ByName("id"), protoreflect.ValueOfString(id))
+		rs.Set(item.Fields().ByName("name"), protoreflect.ValueOfString(name))
+		return rs
+	}
+	srv := grpc.NewServer()
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: string(sd.FullName()),
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{
+			{MethodName: "GetItem", Handler: handler(sd.ParentFile().Messages().ByName("ItemRq"), func(rq *dynamicpb.Message) (*dynamicpb.Message, error) {
+				if field(rq, "id").String() == "unknown" {
+					// AppError as it's passed by kit server
+					st, _ := status.New(codes.NotFound, "item not found").WithDetails(&kitGrpc.AppErrorDetails{Code: "TST-GW-001", Fields: []byte("{}")})
+					return nil, st.Err()
+				}
+				name := "item"
+				if field(rq, "verbose").Bool() {
+					name = "verbose item"
+				}
+				return newItem(field(rq, "id").String(), name), nil
+			})},
+			{MethodName: "CreateItem", Handler: handler(item, func(rq *dynamicpb.Message) (*dynamicpb.Message, error) {
+				return newItem("new", field(rq, "name").String()), nil
+			})},
+			{MethodName: "Echo", Handler: handler(item, func(rq *dynamicpb.Message) (*dynamicpb.Message, error) {
+				return rq, nil
+			})},
+		},
+	}, struct{}{})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() { _ = srv.Serve(lis) }()
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+	return port, srv.Stop
+}
+
+func Test_Gateway(t *testing.T) {
+	sd := testGatewayService(t)
+	port, stop := startTestGatewayServer(t, sd)
+	defer stop()
+
+	cl, err := kitGrpc.NewClient(&kitGrpc.ClientConfig{Host: "127.0.0.1", Port: port})
+	assert.Nil(t, err)
+	defer func() { _ = cl.Conn.Close() }()
+
+	httpSrv := kitHttp.NewHttpServer(&kitHttp.Config{}, testLogger())
+	assert.Nil(t, Register(httpSrv, cl, sd, &Config{Prefix: "/rpc"}, testLogger()))
+	ts := httptest.NewServer(httpSrv.RootRouter)
+	defer ts.Close()
+
+	call := func(method, path, body string) (int, map[string]interface{}) {
+		rq, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		rs, err := http.DefaultClient.Do(rq)
+		assert.Nil(t, err)
+		defer rs.Body.Close()
+		b, _ := ioutil.ReadAll(rs.Body)
+		res := map[string]interface{}{}
+		_ = json.Unmarshal(b, &res)
+		return rs.StatusCode, res
+	}
+
+	// path variable and query parameter
+	st, rs := call(http.MethodGet, "/v1/items/123?verbose=true", "")
+	assert.Equal(t, http.StatusOK, st)
+	assert.Equal(t, "123", rs["id"])
+	assert.Equal(t, "verbose item", rs["name"])
+
+	// unknown query parameters are ignored, path variables aren't overridden by query
+	st, rs = call(http.MethodGet, "/v1/items/123?_=1600000000&id=456", "")
+	assert.Equal(t, http.StatusOK, st)
+	assert.Equal(t, "123", rs["id"])
+
+	// body
+	st, rs = call(http.MethodPost, "/v1/items", `{"name":"created"}`)
+	assert.Equal(t, http.StatusOK, st)
+	assert.Equal(t, "new", rs["id"])
+	assert.Equal(t, "created", rs["name"])
+
+	// convention route
+	st, rs = call(http.MethodPost, "/rpc/kit.test.ItemService/Echo", `{"id":"1","name":"echo"}`)
+	assert.Equal(t, http.StatusOK, st)
+	assert.Equal(t, "echo", rs["name"])
+
+	// AppError returned by the service gets HTTP status from gRPC code
+	st, rs = call(http.MethodGet, "/v1/items/unknown", "")
+	assert.Equal(t, http.StatusNotFound, st)
+	assert.Equal(t, "TST-GW-001", rs["code"])
+	assert.Equal(t, "item not found", rs["message"])
+
+	// invalid request
+	st, rs = call(http.MethodGet, "/v1/items/123?verbose=maybe", "")
+	assert.Equal(t, http.StatusBadRequest, st)
+	assert.Equal(t, ErrCodeGatewayRq, rs["code"])
+}
+
+func Test_Gateway_BodyLimit(t *testing.T) {
+	sd := testGatewayService(t)
+	port, stop := startTestGatewayServer(t, sd)
+	defer stop()
+
+	cl, err := kitGrpc.NewClient(&kitGrpc.ClientConfig{Host: "127.0.0.1", Port: port})
+	assert.Nil(t, err)
+	defer func() { _ = cl.Conn.Close() }()
+
+	call := func(srvLimit, gwLimit int64, body string) (int, string) {
+		httpSrv := kitHttp.NewHttpServer(&kitHttp.Config{MaxBodyBytes: srvLimit}, testLogger())
+		assert.Nil(t, Register(httpSrv, cl, sd, &Config{MaxBodyBytes: gwLimit}, testLogger()))
+		rq := httptest.NewRequest(http.MethodPost, "/v1/items", strings.NewReader(body))
+		// unknown length, so the limit of HTTP server is found when reading body
+		rq.ContentLength = -1
+		rs := httptest.NewRecorder()
+		httpSrv.Srv.Handler.ServeHTTP(rs, rq)
+		return rs.Code, rs.Body.String()
+	}
+	long := `{"name":"` + strings.Repeat("a", 100) + `"}`
+
+	// limit of the gateway
+	st, body := call(0, 32, long)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, st)
+	assert.Contains(t, body, kitHttp.ErrCodeHttpRequestTooLarge)
+
+	// limit of HTTP server isn't converted to bad request
+	st, body = call(32, 0, long)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, st)
+	assert.Contains(t, body, kitHttp.ErrCodeHttpRequestTooLarge)
+
+	st, _ = call(0, 32, `{"name":"ok"}`)
+	assert.Equal(t, http.StatusOK, st)
+}
+
+func Test_Gateway_MuxPath(t *testing.T) {
+	assert.Equal(t, "/v1/items/{id}", muxPath("/v1/items/{id}"))
+	assert.Equal(t, "/v1/{name:items/[^/]+}", muxPath("/v1/{name=items/*}"))
+	assert.Equal(t, "/v1/{path:files/.+}:download", muxPath("/v1/{path=files/**}:download"))
+}
+
+func Test_Gateway_WithHttpStatus(t *testing.T) {
+	cause := er.WithBuilder("TST-GW-002", "item not found").F(er.FF{"id": "1"}).GrpcSt(uint32(codes.NotFound)).Severity(er.SeverityBusiness).Err()
+	err := withHttpStatus(cause)
+
+	appErr, ok := er.Is(err)
+	assert.True(t, ok)
+	assert.Equal(t, "TST-GW-002", appErr.Code())
+	assert.Equal(t, "item not found", appErr.Message())
+	assert.Equal(t, "1", appErr.Fields()["id"])
+	assert.Equal(t, uint32(http.StatusNotFound), *appErr.HttpStatus())
+	assert.Equal(t, er.SeverityBusiness, appErr.Severity())
+	assert.Equal(t, cause, errors.Unwrap(err))
+
+	// severity is derived from HTTP status if it isn't specified
+	appErr, _ = er.Is(withHttpStatus(er.WithBuilder("TST-GW-002", "item not found").GrpcSt(uint32(codes.NotFound)).Err()))
+	assert.Equal(t, er.SeverityWarning, appErr.Severity())
+}
+
+func Test_Gateway_WithHttpStatus_WhenMulti(t *testing.T) {
+	cause := er.NewMulti("TST-GW-003", "validation failed").
+		Add(er.WithBuilder("TST-GW-004", "invalid name").GrpcSt(uint32(codes.InvalidArgument)).Err()).
+		GrpcSt(uint32(codes.InvalidArgument))
+	err := withHttpStatus(cause)
+
+	multi, ok := er.IsMulti(err)
+	assert.True(t, ok)
+	assert.Equal(t, "TST-GW-003", multi.Code())
+	assert.Equal(t, "validation failed", multi.Message())
+	assert.Equal(t, 1, multi.Len())
+	assert.Equal(t, uint32(http.StatusBadRequest), *multi.HttpStatus())
+	assert.Equal(t, uint32(codes.InvalidArgument), *multi.GrpcStatus())
+
+	// explicit HTTP status is kept
+	cause.HttpSt(http.StatusConflict)
+	assert.Equal(t, cause, withHttpStatus(cause))
+}
@@ -0,0 +1,109 @@
+package grpc
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"sync"
+	"time"
+)
+
+const (
+	ReadinessPollInterval = time.Millisecond * 500
+)
+
+// HealthServer is the standard grpc.health.v1 service
+// each sub-service reports its status, the whole server (empty service name) is SERVING only when all reported sub-services are SERVING
+// until anything is reported, the server is NOT_SERVING
+type HealthServer struct {
+	sync.Mutex
+	srv      *health.Server
+	services map[string]bool
+	shutdown bool
+}
+
+// NewHealthServer creates health service and registers it on the given gRPC server
+// the kit server doesn't register it itself, pass Srv of the kit server before it starts listening
+func NewHealthServer(srv *grpc.Server) *HealthServer {
+	h := &HealthServer{srv: health.NewServer(), services: make(map[string]bool)}
+	h.srv.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	grpc_health_v1.RegisterHealthServer(srv, h.srv)
+	return h
+}
+
+// SetServing sets serving status of the sub-service
+// it fails after Shutdown, as statuses cannot be changed anymore
+func (h *HealthServer) SetServing(service string, serving bool) error {
+	h.Lock()
+	defer h.Unlock()
+	if h.shutdown {
+		return ErrGrpcHealthShutdown(service)
+	}
+	h.services[service] = serving
+	h.srv.SetServingStatus(service, servingStatus(serving))
+	all := true
+	for _, s := range h.services {
+		all = all && s
+	}
+	h.srv.SetServingStatus("", servingStatus(all))
+	return nil
+}
+
+// Shutdown sets all services NOT_SERVING, statuses cannot be changed after that
+// call it before the server stops, so clients stop sending new calls
+func (h *HealthServer) Shutdown() {
+	h.Lock()
+	defer h.Unlock()
+	h.shutdown = true
+	h.srv.Shutdown()
+}
+
+func servingStatus(serving bool) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	if serving {
+		return grpc_health_v1.HealthCheckResponse_SERVING
+	}
+	return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+}
+
+// ReadinessConfig makes client readiness be checked with grpc.health.v1 instead of connection state
+type ReadinessConfig struct {
+	// Service - name of the service checked, empty means the whole server
+	Service string
+	// PollInterval - interval between checks, 500ms by default
+	PollInterval time.Duration `config:"poll-interval"`
+}
+
+// AwaitReadiness waits until the remote server is ready
+// if readiness is configured, the health endpoint is polled until it reports SERVING, otherwise connection state is awaited
+func (c *Client) AwaitReadiness(timeout time.Duration) bool {
+	if c.cfg.Readiness == nil {
+		return c.readinessAwaiter.AwaitReadiness(timeout)
+	}
+	return c.awaitHealth(timeout)
+}
+
+func (c *Client) awaitHealth(timeout time.Duration) bool {
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	interval := c.cfg.Readiness.PollInterval
+	if interval <= 0 {
+		interval = ReadinessPollInterval
+	}
+
+	hc := grpc_health_v1.NewHealthClient(c.Conn)
+	for {
+		// wait for connection within the same timeout rather than fail fast
+		rs, err := hc.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: c.cfg.Readiness.Service}, grpc.WaitForReady(true))
+		if err == nil && rs.Status == grpc_health_v1.HealthCheckResponse_SERVING {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package grpc
+
+import (
+	"context"
+	"github.com/exluap/kit/er"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"net"
+	"testing"
+	"time"
+)
+
+func Test_Health_AwaitReadiness(t *testing.T) {
+	srv := grpc.NewServer()
+	hs := NewHealthServer(srv)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+
+	cl, err := NewClient(&ClientConfig{Host: "127.0.0.1", Port: port, Readiness: &ReadinessConfig{PollInterval: time.Millisecond * 10}})
+	assert.Nil(t, err)
+	defer func() { _ = cl.Conn.Close() }()
+
+	// nothing reported yet
+	assert.False(t, cl.AwaitReadiness(time.Millisecond*100))
+
+	assert.Nil(t, hs.SetServing("svc1", true))
+	assert.Nil(t, hs.SetServing("svc2", false))
+	assert.False(t, cl.AwaitReadiness(time.Millisecond*100))
+
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		_ = hs.SetServing("svc2", true)
+	}()
+	assert.True(t, cl.AwaitReadiness(time.Second*5))
+
+	// sub-service status
+	rs, err := grpc_health_v1.NewHealthClient(cl.Conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "svc1"})
+	assert.Nil(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, rs.Status)
+
+	hs.Shutdown()
+	assert.False(t, cl.AwaitReadiness(time.Millisecond*100))
+
+	// statuses cannot be changed after shutdown
+	err = hs.SetServing("svc1", true)
+	assert.True(t, er.HasCode(err, ErrCodeGrpcHealthDown))
+}
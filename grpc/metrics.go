@@ -0,0 +1,104 @@
+package grpc
+
+import (
+	"context"
+	"github.com/exluap/kit/monitoring"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"time"
+)
+
+// client metrics are shared by all the clients and labelled by target
+// so several clients can be passed to the metrics server without duplicate registration
+var (
+	clientRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kit",
+		Subsystem: "grpc_client",
+		Name:      "retries_total",
+		Help:      "Number of retried calls",
+	}, []string{"target", "method"})
+	clientBreakerState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "kit",
+		Subsystem: "grpc_client",
+		Name:      "breaker_state",
+		Help:      "State of circuit breaker: 0 - closed, 1 - open, 2 - half-open",
+	}, []string{"target", "method"})
+	clientBreakerRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "kit",
+		Subsystem: "grpc_client",
+		Name:      "breaker_rejected_total",
+		Help:      "Number of calls rejected by open circuit breaker",
+	}, []string{"target", "method"})
+)
+
+// clientMetrics are metrics of resilience features of gRPC client curried with the client target
+type clientMetrics struct {
+	retries         *prometheus.CounterVec
+	breakerState    *prometheus.GaugeVec
+	breakerRejected *prometheus.CounterVec
+}
+
+func newClientMetrics(target string) *clientMetrics {
+	labels := prometheus.Labels{"target": target}
+	return &clientMetrics{
+		retries:         clientRetries.MustCurryWith(labels),
+		breakerState:    clientBreakerState.MustCurryWith(labels),
+		breakerRejected: clientBreakerRejected.MustCurryWith(labels),
+	}
+}
+
+// GetCollector returns metrics of gRPC clients, so the client can be passed to the metrics server as MetricsProvider
+// metrics are shared by all the clients, the metrics server registers them once
+func (c *Client) GetCollector() monitoring.MetricsCollector {
+	return func() monitoring.MetricsCollection {
+		return monitoring.MetricsCollection{clientRetries, clientBreakerState, clientBreakerRejected}
+	}
+}
+
+// serverDuration is shared by all the server interceptors, so they can be passed to the metrics server without duplicate registration
+var serverDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "kit",
+	Subsystem: "grpc_server",
+	Name:      "handling_seconds",
+	Help:      "Duration of handling calls",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"method", "code"})
+
+// serverMetrics are metrics of gRPC server calls
+type serverMetrics struct {
+	duration *prometheus.HistogramVec
+}
+
+func newServerMetrics() *serverMetrics {
+	return &serverMetrics{duration: serverDuration}
+}
+
+func (m *serverMetrics) observe(method string, start time.Time, err error) {
+	m.duration.WithLabelValues(method, errCode(err).String()).Observe(time.Since(start).Seconds())
+}
+
+func (m *serverMetrics) unaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		rs, err := handler(ctx, req)
+		m.observe(info.FullMethod, start, err)
+		return rs, err
+	}
+}
+
+func (m *serverMetrics) streamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		m.observe(info.FullMethod, start, err)
+		return err
+	}
+}
+
+// GetCollector returns server metrics, so interceptors can be passed to the metrics server as MetricsProvider
+// metrics are shared by all the interceptors, the metrics server registers them once
+func (s *ServerInterceptors) GetCollector() monitoring.MetricsCollector {
+	return func() monitoring.MetricsCollection {
+		return monitoring.MetricsCollection{s.metrics.duration}
+	}
+}
@@ -0,0 +1,34 @@
+package grpc
+
+import (
+	"github.com/exluap/kit/monitoring"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Metrics_SeveralClients(t *testing.T) {
+	cl1, err := NewClient(&ClientConfig{Host: "127.0.0.1", Port: "50001"})
+	assert.Nil(t, err)
+	defer func() { _ = cl1.Conn.Close() }()
+	cl2, err := NewClient(&ClientConfig{Host: "127.0.0.1", Port: "50001"})
+	assert.Nil(t, err)
+	defer func() { _ = cl2.Conn.Close() }()
+
+	srv := monitoring.NewMetricsServer(testLogger())
+	assert.Nil(t, srv.Init(&monitoring.Config{Port: "9999"}, cl1, cl2))
+}
+
+func Test_Metrics_SeveralServerInterceptors(t *testing.T) {
+	si1 := NewServerInterceptors(&ServerInterceptorsConfig{Metrics: true}, testLogger())
+	si2 := NewServerInterceptors(&ServerInterceptorsConfig{Metrics: true}, testLogger())
+
+	srv := monitoring.NewMetricsServer(testLogger())
+	assert.Nil(t, srv.Init(&monitoring.Config{Port: "9999"}, si1, si2))
+}
+
+func Test_Metrics_ClientName(t *testing.T) {
+	assert.Equal(t, "svc", clientName(&ClientConfig{Name: "svc", Endpoints: []string{"a:1", "b:2"}}, "kit-static:///endpoints"))
+	assert.Equal(t, "a:1,b:2", clientName(&ClientConfig{Endpoints: []string{"a:1", "b:2"}}, "kit-static:///endpoints"))
+	assert.Equal(t, "c:3", clientName(&ClientConfig{Resolver: NewStaticResolver("c:3")}, "kit-static:///endpoints"))
+	assert.Equal(t, "dns:///svc:9000", clientName(&ClientConfig{Target: "dns:///svc:9000"}, "dns:///svc:9000"))
+}
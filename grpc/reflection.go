@@ -0,0 +1,21 @@
+package grpc
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// ReflectionConfig toggles gRPC server reflection
+type ReflectionConfig struct {
+	// Enabled - if set, reflection service is registered, so tools like grpcurl can discover services
+	// it exposes full API schema, so it's usually disabled in production
+	Enabled bool
+}
+
+// RegisterReflection registers reflection service on gRPC server if it's enabled
+// pass Srv of the kit server, it must be called before the server starts listening
+func RegisterReflection(srv *grpc.Server, cfg *ReflectionConfig) {
+	if cfg != nil && cfg.Enabled {
+		reflection.Register(srv)
+	}
+}
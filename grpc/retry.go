@@ -0,0 +1,173 @@
+package grpc
+
+import (
+	"context"
+	"github.com/exluap/kit/er"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	RetryBackoff    = time.Millisecond * 100
+	RetryMaxBackoff = time.Second * 5
+)
+
+// RetryConfig is retry policy of unary calls
+type RetryConfig struct {
+	// MaxAttempts - max number of attempts including the first one
+	MaxAttempts int `config:"max-attempts"`
+	// Backoff - initial backoff between attempts, it's doubled each attempt, 100ms by default
+	Backoff time.Duration
+	// MaxBackoff - max backoff between attempts, 5s by default
+	MaxBackoff time.Duration `config:"max-backoff"`
+	// Codes - gRPC codes which are retried (e.g. UNAVAILABLE), UNAVAILABLE by default
+	// AppError marked as retryable is retried regardless of its code
+	Codes []string
+	// HedgingDelay - if specified, the next attempt is sent if the previous one hasn't completed within the delay
+	// the first completed attempt wins, use it only for idempotent methods
+	HedgingDelay time.Duration `config:"hedging-delay"`
+}
+
+// retryPolicy is a parsed retry configuration
+type retryPolicy struct {
+	cfg        *RetryConfig
+	codes      map[codes.Code]struct{}
+	backoff    time.Duration
+	maxBackoff time.Duration
+}
+
+func newRetryPolicy(cfg *RetryConfig) (*retryPolicy, error) {
+	p := &retryPolicy{
+		cfg:        cfg,
+		codes:      make(map[codes.Code]struct{}),
+		backoff:    cfg.Backoff,
+		maxBackoff: cfg.MaxBackoff,
+	}
+	if p.backoff <= 0 {
+		p.backoff = RetryBackoff
+	}
+	if p.maxBackoff <= 0 {
+		p.maxBackoff = RetryMaxBackoff
+	}
+	if len(cfg.Codes) == 0 {
+		p.codes[codes.Unavailable] = struct{}{}
+	}
+	for _, c := range cfg.Codes {
+		var code codes.Code
+		if err := code.UnmarshalJSON([]byte(`"` + strings.ToUpper(c) + `"`)); err != nil {
+			return nil, ErrGrpcRetryConfig(err)
+		}
+		p.codes[code] = struct{}{}
+	}
+	return p, nil
+}
+
+// retryable checks if call failed with error can be retried
+func (p *retryPolicy) retryable(err error) bool {
+	appErr := toAppError(err)
+	if er.IsRetryable(appErr) {
+		return true
+	}
+	_, ok := p.codes[errCode(appErr)]
+	return ok
+}
+
+// unaryRetryInterceptor retries failed calls according to the policy
+func (c *Client) unaryRetryInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if c.retry.cfg.HedgingDelay > 0 {
+			return c.hedge(ctx, method, req, reply, cc, invoker, opts...)
+		}
+		backoff := c.retry.backoff
+		for attempt := 1; ; attempt++ {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil || attempt >= c.retry.cfg.MaxAttempts || !c.retry.retryable(err) {
+				return err
+			}
+			// server might say when to retry
+			wait := backoff
+			if appErr, ok := er.Is(toAppError(err)); ok && appErr.RetryAfter() > 0 {
+				wait = appErr.RetryAfter()
+			}
+			if wait > c.retry.maxBackoff {
+				wait = c.retry.maxBackoff
+			}
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(wait):
+			}
+			c.metrics.retries.WithLabelValues(method).Inc()
+			if backoff *= 2; backoff > c.retry.maxBackoff {
+				backoff = c.retry.maxBackoff
+			}
+		}
+	}
+}
+
+type hedgeResult struct {
+	reply interface{}
+	err   error
+}
+
+// hedge sends attempts concurrently with a delay, the first successful or non-retryable result wins
+// each attempt gets its own reply message, the winner is merged to the reply of the caller
+func (c *Client) hedge(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+
+	// pending attempts are cancelled as soon as the result is taken
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult, c.retry.cfg.MaxAttempts)
+	send := func() {
+		rs := reflect.New(reflect.TypeOf(reply).Elem()).Interface()
+		go func() {
+			results <- hedgeResult{reply: rs, err: invoker(ctx, method, req, rs, cc, opts...)}
+		}()
+	}
+
+	send()
+	sent, completed := 1, 0
+	var lastErr error
+	timer := time.NewTimer(c.retry.cfg.HedgingDelay)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			if sent < c.retry.cfg.MaxAttempts {
+				c.metrics.retries.WithLabelValues(method).Inc()
+				send()
+				sent++
+				timer.Reset(c.retry.cfg.HedgingDelay)
+			}
+		case rs := <-results:
+			completed++
+			if rs.err == nil {
+				reply.(proto.Message).Reset()
+				proto.Merge(reply.(proto.Message), rs.reply.(proto.Message))
+				return nil
+			}
+			lastErr = rs.err
+			if !c.retry.retryable(rs.err) || (completed == sent && sent >= c.retry.cfg.MaxAttempts) {
+				return rs.err
+			}
+			// failed attempt is replaced immediately
+			if completed == sent && sent < c.retry.cfg.MaxAttempts {
+				c.metrics.retries.WithLabelValues(method).Inc()
+				send()
+				sent++
+				timer.Reset(c.retry.cfg.HedgingDelay)
+			}
+		case <-ctx.Done():
+			if lastErr != nil {
+				return lastErr
+			}
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package grpc
+
+import (
+	"context"
+	"github.com/exluap/kit/er"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestRetryClient(t *testing.T, cfg *RetryConfig) *Client {
+	policy, err := newRetryPolicy(cfg)
+	assert.Nil(t, err)
+	return &Client{cfg: &ClientConfig{Retry: cfg}, retry: policy, metrics: newClientMetrics("test")}
+}
+
+func Test_Retry_WhenUnavailable(t *testing.T) {
+	c := newTestRetryClient(t, &RetryConfig{MaxAttempts: 3, Backoff: time.Millisecond})
+	var calls int32
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return status.Error(codes.Unavailable, "unavailable")
+		}
+		return nil
+	}
+	err := c.unaryRetryInterceptor()(context.Background(), "/test/Method", nil, nil, nil, invoker)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(3), calls)
+}
+
+func Test_Retry_WhenNotRetryable(t *testing.T) {
+	c := newTestRetryClient(t, &RetryConfig{MaxAttempts: 3, Backoff: time.Millisecond})
+	var calls int32
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		atomic.AddInt32(&calls, 1)
+		return status.Error(codes.InvalidArgument, "invalid")
+	}
+	err := c.unaryRetryInterceptor()(context.Background(), "/test/Method", nil, nil, nil, invoker)
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), calls)
+}
+
+func Test_Retry_WhenRetryableAppError(t *testing.T) {
+	c := newTestRetryClient(t, &RetryConfig{MaxAttempts: 2, Backoff: time.Millisecond})
+	var calls int32
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		atomic.AddInt32(&calls, 1)
+		return er.WithBuilder("TST-001", "busy").GrpcSt(uint32(codes.Aborted)).Retryable().Err()
+	}
+	err := c.unaryRetryInterceptor()(context.Background(), "/test/Method", nil, nil, nil, invoker)
+	assert.True(t, er.HasCode(err, "TST-001"))
+	assert.Equal(t, int32(2), calls)
+}
+
+func Test_Retry_WhenInvalidCodes(t *testing.T) {
+	_, err := newRetryPolicy(&RetryConfig{MaxAttempts: 2, Codes: []string{"unknown-code"}})
+	assert.True(t, er.HasCode(err, ErrCodeGrpcRetryConfig))
+	_, err = newRetryPolicy(&RetryConfig{MaxAttempts: 2, Codes: []string{"unavailable", "ABORTED"}})
+	assert.Nil(t, err)
+}
+
+func Test_Retry_Hedging(t *testing.T) {
+	c := newTestRetryClient(t, &RetryConfig{MaxAttempts: 2, HedgingDelay: time.Millisecond * 10})
+	var calls int32
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		// the first attempt hangs, the hedged one responds
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		reply.(*grpc_health_v1.HealthCheckResponse).Status = grpc_health_v1.HealthCheckResponse_SERVING
+		return nil
+	}
+	rs := &grpc_health_v1.HealthCheckResponse{}
+	err := c.unaryRetryInterceptor()(context.Background(), "/test/Method", nil, rs, nil, invoker)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, rs.Status)
+}
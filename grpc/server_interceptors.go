@@ -0,0 +1,256 @@
+package grpc
+
+import (
+	"context"
+	kitContext "github.com/exluap/kit/context"
+	"github.com/exluap/kit/er"
+	"github.com/exluap/kit/log"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// UnaryServerErrLogInterceptor logs errors returned by unary handlers
+// log level is chosen by severity of error, so expected business errors don't spam alerting
+func UnaryServerErrLogInterceptor(logger log.CLoggerFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		rs, err := handler(ctx, req)
+		if err != nil {
+			logServerErr(logger, info.FullMethod, err)
+		}
+		return rs, err
+	}
+}
+
+// StreamServerErrLogInterceptor logs errors returned by stream handlers
+// log level is chosen by severity of error, so expected business errors don't spam alerting
+func StreamServerErrLogInterceptor(logger log.CLoggerFunc) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, ss)
+		if err != nil {
+			logServerErr(logger, info.FullMethod, err)
+		}
+		return err
+	}
+}
+
+func logServerErr(logger log.CLoggerFunc, method string, err error) {
+	l := logger().Pr("grpc").Cmp("server").Mth(method).E(err)
+	// stack is useful only for real faults
+	if er.SeverityOf(err) == er.SeverityCritical {
+		l.St()
+	}
+	l.ErrSev()
+}
+
+// UnaryServerRequestContextInterceptor takes request context from incoming metadata
+// in strict mode, calls with invalid request context are rejected, in lenient mode problems are just logged
+func UnaryServerRequestContextInterceptor(mode kitContext.DecodeMode, logger log.CLoggerFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := decodeRequestCtx(ctx, mode, logger, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerRequestContextInterceptor takes request context from incoming metadata for stream calls
+func StreamServerRequestContextInterceptor(mode kitContext.DecodeMode, logger log.CLoggerFunc) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := decodeRequestCtx(ss.Context(), mode, logger, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		wrapped := grpc_middleware.WrapServerStream(ss)
+		wrapped.WrappedContext = ctx
+		return handler(srv, wrapped)
+	}
+}
+
+func decodeRequestCtx(ctx context.Context, mode kitContext.DecodeMode, logger log.CLoggerFunc, method string) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx, nil
+	}
+	ctx, err := kitContext.DecodeGrpcMD(ctx, md, mode)
+	if err != nil {
+		err = ErrGrpcRequestCtx(err, method)
+		if mode == kitContext.DecodeStrict {
+			return nil, err
+		}
+		logger().Pr("grpc").Cmp("server").Mth(method).C(ctx).E(err).Warn()
+	}
+	return ctx, nil
+}
+
+// ServerInterceptorsConfig configures kit server interceptors
+type ServerInterceptorsConfig struct {
+	// Logging - logs method, duration and status code of each call
+	Logging bool
+	// Metrics - records latency histograms by method and code
+	Metrics bool
+	// Validation - calls Validate() on request messages which implement it
+	Validation bool
+}
+
+// ServerInterceptors is a configured set of server interceptors
+// it's MetricsProvider, so it can be passed to the metrics server
+type ServerInterceptors struct {
+	cfg     *ServerInterceptorsConfig
+	logger  log.CLoggerFunc
+	metrics *serverMetrics
+}
+
+func NewServerInterceptors(cfg *ServerInterceptorsConfig, logger log.CLoggerFunc) *ServerInterceptors {
+	return &ServerInterceptors{cfg: cfg, logger: logger, metrics: newServerMetrics()}
+}
+
+// Unary returns unary interceptors in order: logging, metrics, validation
+func (s *ServerInterceptors) Unary() []grpc.UnaryServerInterceptor {
+	var res []grpc.UnaryServerInterceptor
+	if s.cfg.Logging {
+		res = append(res, UnaryServerLogInterceptor(s.logger))
+	}
+	if s.cfg.Metrics {
+		res = append(res, s.metrics.unaryServerInterceptor())
+	}
+	if s.cfg.Validation {
+		res = append(res, UnaryServerValidationInterceptor())
+	}
+	return res
+}
+
+// Stream returns stream interceptors in order: logging, metrics, validation
+func (s *ServerInterceptors) Stream() []grpc.StreamServerInterceptor {
+	var res []grpc.StreamServerInterceptor
+	if s.cfg.Logging {
+		res = append(res, StreamServerLogInterceptor(s.logger))
+	}
+	if s.cfg.Metrics {
+		res = append(res, s.metrics.streamServerInterceptor())
+	}
+	if s.cfg.Validation {
+		res = append(res, StreamServerValidationInterceptor())
+	}
+	return res
+}
+
+// ServerOptions returns options to pass to grpc.NewServer
+// status interceptors go first, so AppError returned by handlers or by any interceptor including ones chained later
+// reaches the client with its gRPC status
+func (s *ServerInterceptors) ServerOptions() []grpc.ServerOption {
+	unary := append([]grpc.UnaryServerInterceptor{UnaryServerStatusInterceptor()}, s.Unary()...)
+	stream := append([]grpc.StreamServerInterceptor{StreamServerStatusInterceptor()}, s.Stream()...)
+	return []grpc.ServerOption{grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...)}
+}
+
+// UnaryServerStatusInterceptor converts errors of unary calls to gRPC status with AppError details
+func UnaryServerStatusInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		rs, err := handler(ctx, req)
+		return rs, serverStatus(err)
+	}
+}
+
+// StreamServerStatusInterceptor converts errors of stream calls to gRPC status with AppError details
+func StreamServerStatusInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return serverStatus(handler(srv, ss))
+	}
+}
+
+// serverStatus converts error to gRPC status, errors which already are gRPC status are kept
+func serverStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return toGrpcStatus(err)
+}
+
+// UnaryServerLogInterceptor logs each unary call with its duration and status code
+func UnaryServerLogInterceptor(logger log.CLoggerFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		rs, err := handler(ctx, req)
+		logServerCall(logger, ctx, info.FullMethod, start, err)
+		return rs, err
+	}
+}
+
+// StreamServerLogInterceptor logs each stream call with its duration and status code
+func StreamServerLogInterceptor(logger log.CLoggerFunc) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logServerCall(logger, ss.Context(), info.FullMethod, start, err)
+		return err
+	}
+}
+
+// logServerCall logs successful calls at info level, failed calls at level chosen by severity of error
+func logServerCall(logger log.CLoggerFunc, ctx context.Context, method string, start time.Time, err error) {
+	l := logger().Pr("grpc").Cmp("server").Mth(method).C(ctx).
+		F(log.FF{"ms": time.Since(start).Milliseconds(), "code": errCode(err).String()})
+	if err != nil {
+		l.E(err).ErrSev("call")
+		return
+	}
+	l.Inf("call")
+}
+
+// validator is implemented by request messages which can check themselves
+type validator interface {
+	Validate() error
+}
+
+// UnaryServerValidationInterceptor validates requests implementing Validate() error
+func UnaryServerValidationInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := validate(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerValidationInterceptor validates each received message implementing Validate() error
+func StreamServerValidationInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &validatingServerStream{ServerStream: ss, method: info.FullMethod})
+	}
+}
+
+type validatingServerStream struct {
+	grpc.ServerStream
+	method string
+}
+
+func (s *validatingServerStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return validate(s.Context(), s.method, m)
+}
+
+// validate calls Validate() if the message implements it
+// AppError with gRPC status is returned as is, other errors are converted to InvalidArgument AppError
+func validate(ctx context.Context, method string, m interface{}) error {
+	v, ok := m.(validator)
+	if !ok {
+		return nil
+	}
+	err := v.Validate()
+	if err == nil {
+		return nil
+	}
+	if appErr, ok := er.Is(err); ok && appErr.GrpcStatus() != nil {
+		return err
+	}
+	return ErrGrpcValidation(err, ctx, method)
+}
@@ -0,0 +1,123 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/exluap/kit/er"
+	"github.com/exluap/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func testLogger() log.CLoggerFunc {
+	return func() log.CLogger {
+		return log.L(log.Init(&log.Config{Level: log.TraceLevel}))
+	}
+}
+
+type testValidatedRq struct {
+	err error
+}
+
+func (r *testValidatedRq) Validate() error {
+	return r.err
+}
+
+func Test_ServerInterceptors_Validation(t *testing.T) {
+	interceptor := UnaryServerValidationInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	rs, err := interceptor(context.Background(), &testValidatedRq{}, info, handler)
+	assert.Nil(t, err)
+	assert.Equal(t, "ok", rs)
+
+	// messages without Validate() are passed
+	_, err = interceptor(context.Background(), "rq", info, handler)
+	assert.Nil(t, err)
+
+	_, err = interceptor(context.Background(), &testValidatedRq{err: errors.New("name is empty")}, info, handler)
+	assert.True(t, er.HasCode(err, ErrCodeGrpcValidation))
+	assert.Equal(t, codes.InvalidArgument, errCode(err))
+
+	// AppError with gRPC status is kept
+	_, err = interceptor(context.Background(), &testValidatedRq{err: er.WithBuilder("TST-001", "invalid").GrpcSt(uint32(codes.FailedPrecondition)).Err()}, info, handler)
+	assert.True(t, er.HasCode(err, "TST-001"))
+}
+
+func Test_ServerInterceptors_Chain(t *testing.T) {
+	si := NewServerInterceptors(&ServerInterceptorsConfig{Logging: true, Metrics: true, Validation: true}, testLogger())
+	assert.Len(t, si.Unary(), 3)
+	assert.Len(t, si.Stream(), 3)
+	assert.Len(t, NewServerInterceptors(&ServerInterceptorsConfig{}, testLogger()).Unary(), 0)
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/test/Method"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	for _, i := range si.Unary() {
+		_, _ = i(context.Background(), &testValidatedRq{}, info, handler)
+	}
+	_, _ = si.Unary()[1](context.Background(), &testValidatedRq{}, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, er.WithBuilder("TST-001", "not found").GrpcSt(uint32(codes.NotFound)).Err()
+	})
+
+	collection := si.GetCollector()()
+	assert.Len(t, collection, 1)
+	// a series per method and code
+	assert.Equal(t, 2, testutil.CollectAndCount(collection[0]))
+}
+
+func Test_ServerInterceptors_LogLevel(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := log.Init(&log.Config{Level: log.InfoLevel, Format: log.FormatterJson})
+	logger.Logrus.SetOutput(buf)
+	interceptor := UnaryServerLogInterceptor(func() log.CLogger { return log.L(logger) })
+	info := &grpc.UnaryServerInfo{FullMethod: "/test/Method"}
+
+	levels := map[error]string{
+		nil: "info",
+		er.WithBuilder("TST-001", "not found").Severity(er.SeverityBusiness).Err(): "info",
+		er.WithBuilder("TST-002", "invalid").Severity(er.SeverityWarning).Err():    "warning",
+		errors.New("fault"): "error",
+	}
+	for err, level := range levels {
+		buf.Reset()
+		_, _ = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) { return nil, err })
+		entry := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+		assert.Equal(t, "call", entry["msg"])
+		assert.Equal(t, level, entry["level"])
+	}
+}
+
+func Test_ServerInterceptors_Status(t *testing.T) {
+	si := NewServerInterceptors(&ServerInterceptorsConfig{Logging: true}, testLogger())
+	// interceptor chained after kit ones rejects the call the way authorization does
+	deny := grpc.ChainUnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return nil, er.WithBuilder("TST-ST-001", "access denied").GrpcSt(uint32(codes.PermissionDenied)).Err()
+	})
+	port, stop := startTestServer(t, append(si.ServerOptions(), deny, grpc.UnknownServiceHandler(func(srv interface{}, ss grpc.ServerStream) error {
+		return er.WithBuilder("TST-ST-002", "invalid").GrpcSt(uint32(codes.InvalidArgument)).Err()
+	}))...)
+	defer stop()
+
+	conn, err := grpc.Dial("127.0.0.1:"+port, grpc.WithInsecure())
+	assert.Nil(t, err)
+	defer func() { _ = conn.Close() }()
+
+	_, err = grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, "access denied", status.Convert(err).Message())
+	assert.True(t, er.HasCode(toAppError(err), "TST-ST-001"))
+
+	stream, err := conn.NewStream(context.Background(), &grpc.StreamDesc{ServerStreams: true}, "/kit.test.Unknown/Call")
+	assert.Nil(t, err)
+	err = stream.RecvMsg(&grpc_health_v1.HealthCheckResponse{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
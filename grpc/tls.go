@@ -0,0 +1,242 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"google.golang.org/grpc/credentials"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	TLSReloadInterval = time.Second * 10
+)
+
+// TLSConfig is TLS configuration of gRPC client or server
+// certificates are reloaded from disk when files change, so they can be rotated without restart
+type TLSConfig struct {
+	// CertFile - path to PEM certificate, mandatory for server, for client it's presented to server if mTLS is required
+	CertFile string `config:"cert-file"`
+	// KeyFile - path to PEM private key of the certificate
+	KeyFile string `config:"key-file"`
+	// CAFile - path to PEM CA bundle
+	// client verifies server certificate against it (system roots are used if not specified)
+	// server verifies client certificates against it if ClientAuth is set
+	CAFile string `config:"ca-file"`
+	// ServerName - client only, overrides server name used to verify server certificate
+	ServerName string `config:"server-name"`
+	// ClientAuth - server only, requires and verifies client certificates (mTLS)
+	ClientAuth bool `config:"client-auth"`
+	// ReloadInterval - how often files are checked for changes, 10s by default
+	ReloadInterval time.Duration `config:"reload-interval"`
+}
+
+// NewServerCredentials creates server transport credentials
+// pass it to the server with grpc.Creds option
+func NewServerCredentials(cfg *TLSConfig) (credentials.TransportCredentials, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, ErrGrpcTLS(errors.New("cert and key files must be specified"))
+	}
+	if cfg.ClientAuth && cfg.CAFile == "" {
+		return nil, ErrGrpcTLS(errors.New("CA file must be specified to verify client certificates"))
+	}
+	store, err := newCertStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tlsCfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// config is built per handshake, so reloaded certificates are taken
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := store.get()
+			c := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				NextProtos:   []string{"h2"},
+			}
+			if cfg.ClientAuth {
+				c.ClientAuth = tls.RequireAndVerifyClientCert
+				c.ClientCAs = pool
+			}
+			return c, nil
+		},
+	}
+	return credentials.NewTLS(tlsCfg), nil
+}
+
+// NewClientCredentials creates client transport credentials
+func NewClientCredentials(cfg *TLSConfig) (credentials.TransportCredentials, error) {
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, ErrGrpcTLS(errors.New("both cert and key files must be specified"))
+	}
+	store, err := newCertStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tlsCfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: cfg.ServerName,
+	}
+	if cfg.CertFile != "" {
+		tlsCfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, _ := store.get()
+			return cert, nil
+		}
+	}
+	if cfg.CAFile != "" {
+		// standard verification takes roots fixed at creation time, so verification is done manually against reloaded CA
+		tlsCfg.InsecureSkipVerify = true
+		return &clientCredentials{TransportCredentials: credentials.NewTLS(tlsCfg), cfg: tlsCfg, store: store}, nil
+	}
+	return credentials.NewTLS(tlsCfg), nil
+}
+
+// clientCredentials verifies server certificate against reloaded CA
+// certificate is verified against ServerName or, if it isn't specified, against host or IP of the dialed authority
+type clientCredentials struct {
+	credentials.TransportCredentials
+	cfg   *tls.Config
+	store *certStore
+}
+
+func (c *clientCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	name := c.cfg.ServerName
+	if name == "" {
+		name = authority
+		if host, _, err := net.SplitHostPort(authority); err == nil {
+			name = host
+		}
+	}
+	// fail closed, certificate cannot be verified without name
+	if name == "" {
+		return nil, nil, ErrGrpcTLS(errors.New("server name is unknown, specify ServerName"))
+	}
+	cfg := c.cfg.Clone()
+	cfg.ServerName = name
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		return c.verify(cs, name)
+	}
+	return credentials.NewTLS(cfg).ClientHandshake(ctx, authority, rawConn)
+}
+
+func (c *clientCredentials) verify(cs tls.ConnectionState, name string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("server presented no certificate")
+	}
+	_, pool := c.store.get()
+	opts := x509.VerifyOptions{
+		DNSName:       name,
+		Roots:         pool,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(opts)
+	return err
+}
+
+func (c *clientCredentials) Clone() credentials.TransportCredentials {
+	return &clientCredentials{TransportCredentials: c.TransportCredentials.Clone(), cfg: c.cfg.Clone(), store: c.store}
+}
+
+func (c *clientCredentials) OverrideServerName(name string) error {
+	c.cfg.ServerName = name
+	return c.TransportCredentials.OverrideServerName(name)
+}
+
+// certStore keeps certificate and CA pool loaded from files and reloads them when files change
+type certStore struct {
+	sync.Mutex
+	cfg       *TLSConfig
+	interval  time.Duration
+	cert      *tls.Certificate
+	pool      *x509.CertPool
+	modTimes  map[string]time.Time
+	checkedAt time.Time
+}
+
+func newCertStore(cfg *TLSConfig) (*certStore, error) {
+	s := &certStore{cfg: cfg, interval: cfg.ReloadInterval}
+	if s.interval == 0 {
+		s.interval = TLSReloadInterval
+	}
+	if err := s.load(); err != nil {
+		return nil, ErrGrpcTLS(err)
+	}
+	return s, nil
+}
+
+// get returns actual certificate and CA pool
+// if files have been changed but they cannot be loaded, the previous ones are returned
+func (s *certStore) get() (*tls.Certificate, *x509.CertPool) {
+	s.Lock()
+	defer s.Unlock()
+	if time.Since(s.checkedAt) >= s.interval {
+		s.checkedAt = time.Now()
+		if s.changed() {
+			_ = s.load()
+		}
+	}
+	return s.cert, s.pool
+}
+
+func (s *certStore) files() []string {
+	var res []string
+	for _, f := range []string{s.cfg.CertFile, s.cfg.KeyFile, s.cfg.CAFile} {
+		if f != "" {
+			res = append(res, f)
+		}
+	}
+	return res
+}
+
+func (s *certStore) changed() bool {
+	for _, f := range s.files() {
+		if fi, err := os.Stat(f); err == nil && !fi.ModTime().Equal(s.modTimes[f]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *certStore) load() error {
+
+	modTimes := make(map[string]time.Time)
+	for _, f := range s.files() {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		modTimes[f] = fi.ModTime()
+	}
+
+	var cert *tls.Certificate
+	if s.cfg.CertFile != "" {
+		c, err := tls.LoadX509KeyPair(s.cfg.CertFile, s.cfg.KeyFile)
+		if err != nil {
+			return err
+		}
+		cert = &c
+	}
+
+	var pool *x509.CertPool
+	if s.cfg.CAFile != "" {
+		data, err := ioutil.ReadFile(s.cfg.CAFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return errors.New("no certificates found in CA file")
+		}
+	}
+
+	s.cert, s.pool, s.modTimes, s.checkedAt = cert, pool, modTimes, time.Now()
+	return nil
+}
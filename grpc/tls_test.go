@@ -0,0 +1,210 @@
+package grpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// genCert generates certificate signed by parent, if parent is nil, it's self-signed CA
+func genCert(t *testing.T, cn string, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{cn},
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if ip := net.ParseIP(cn); ip != nil {
+		tpl.IPAddresses = []net.IP{ip}
+	}
+	signer, signerKey := tpl, key
+	if parent == nil {
+		tpl.IsCA, tpl.BasicConstraintsValid = true, true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, signer, &key.PublicKey, signerKey)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	return &testCert{cert: cert, key: key}
+}
+
+// write writes certificate and key to the dir and returns paths
+func (c *testCert) write(t *testing.T, dir, name string) (string, string) {
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	assert.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0600))
+	keyDer, err := x509.MarshalECPrivateKey(c.key)
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func checkHealth(cl *Client) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	_, err := grpc_health_v1.NewHealthClient(cl.Conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{}, grpc.WaitForReady(false))
+	return err
+}
+
+func Test_TLS_Mutual(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kit-tls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := genCert(t, "ca", nil)
+	caFile, _ := ca.write(t, dir, "ca")
+	srvCert, srvKey := genCert(t, "localhost", ca).write(t, dir, "server")
+	clCert, clKey := genCert(t, "client", ca).write(t, dir, "client")
+
+	creds, err := NewServerCredentials(&TLSConfig{CertFile: srvCert, KeyFile: srvKey, CAFile: caFile, ClientAuth: true})
+	assert.Nil(t, err)
+	port, stop := startTestServer(t, grpc.Creds(creds))
+	defer stop()
+
+	// client with certificate
+	cl, err := NewClient(&ClientConfig{Host: "127.0.0.1", Port: port, TLS: &TLSConfig{CertFile: clCert, KeyFile: clKey, CAFile: caFile, ServerName: "localhost"}})
+	assert.Nil(t, err)
+	assert.Nil(t, checkHealth(cl))
+	_ = cl.Conn.Close()
+
+	// client without certificate is rejected
+	cl, err = NewClient(&ClientConfig{Host: "127.0.0.1", Port: port, TLS: &TLSConfig{CAFile: caFile, ServerName: "localhost"}})
+	assert.Nil(t, err)
+	assert.Error(t, checkHealth(cl))
+	_ = cl.Conn.Close()
+
+	// server isn't trusted by the client
+	otherCaFile, _ := genCert(t, "other", nil).write(t, dir, "other")
+	cl, err = NewClient(&ClientConfig{Host: "127.0.0.1", Port: port, TLS: &TLSConfig{CertFile: clCert, KeyFile: clKey, CAFile: otherCaFile, ServerName: "localhost"}})
+	assert.Nil(t, err)
+	assert.Error(t, checkHealth(cl))
+	_ = cl.Conn.Close()
+}
+
+func Test_TLS_ServerName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kit-tls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := genCert(t, "ca", nil)
+	caFile, _ := ca.write(t, dir, "ca")
+	srvCert, srvKey := genCert(t, "localhost", ca).write(t, dir, "server")
+	creds, err := NewServerCredentials(&TLSConfig{CertFile: srvCert, KeyFile: srvKey})
+	assert.Nil(t, err)
+	port, stop := startTestServer(t, grpc.Creds(creds))
+	defer stop()
+
+	check := func(tlsCfg *TLSConfig) error {
+		cl, err := NewClient(&ClientConfig{Host: "127.0.0.1", Port: port, TLS: tlsCfg})
+		assert.Nil(t, err)
+		defer func() { _ = cl.Conn.Close() }()
+		return checkHealth(cl)
+	}
+
+	assert.Nil(t, check(&TLSConfig{CAFile: caFile, ServerName: "localhost"}))
+	// certificate signed by the trusted CA, but issued for another name
+	assert.Error(t, check(&TLSConfig{CAFile: caFile, ServerName: "other"}))
+	// dialed by IP without ServerName, the certificate has no such IP
+	assert.Error(t, check(&TLSConfig{CAFile: caFile}))
+
+	// certificate issued for the IP
+	ipCert, ipKey := genCert(t, "127.0.0.1", ca).write(t, dir, "ip")
+	creds, err = NewServerCredentials(&TLSConfig{CertFile: ipCert, KeyFile: ipKey})
+	assert.Nil(t, err)
+	ipPort, ipStop := startTestServer(t, grpc.Creds(creds))
+	defer ipStop()
+	cl, err := NewClient(&ClientConfig{Host: "127.0.0.1", Port: ipPort, TLS: &TLSConfig{CAFile: caFile}})
+	assert.Nil(t, err)
+	defer func() { _ = cl.Conn.Close() }()
+	assert.Nil(t, checkHealth(cl))
+}
+
+func Test_TLS_Endpoints(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kit-tls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := genCert(t, "ca", nil)
+	caFile, _ := ca.write(t, dir, "ca")
+	srvCert, srvKey := genCert(t, "svc.local", ca).write(t, dir, "server")
+	creds, err := NewServerCredentials(&TLSConfig{CertFile: srvCert, KeyFile: srvKey})
+	assert.Nil(t, err)
+	port1, stop1 := startTestServer(t, grpc.Creds(creds))
+	defer stop1()
+	port2, stop2 := startTestServer(t, grpc.Creds(creds))
+	defer stop2()
+
+	// server name is used both as authority and to verify certificates of all the endpoints
+	cl, err := NewClient(&ClientConfig{Endpoints: []string{"127.0.0.1:" + port1, "127.0.0.1:" + port2}, TLS: &TLSConfig{CAFile: caFile, ServerName: "svc.local"}})
+	assert.Nil(t, err)
+	defer func() { _ = cl.Conn.Close() }()
+	assert.Nil(t, checkHealth(cl))
+}
+
+func Test_TLS_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kit-tls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := genCert(t, "ca", nil)
+	first := genCert(t, "localhost", ca)
+	certFile, keyFile := first.write(t, dir, "server")
+
+	store, err := newCertStore(&TLSConfig{CertFile: certFile, KeyFile: keyFile, ReloadInterval: time.Millisecond})
+	assert.Nil(t, err)
+	cert, _ := store.get()
+	assert.Equal(t, first.cert.Raw, cert.Certificate[0])
+
+	second := genCert(t, "localhost", ca)
+	second.write(t, dir, "server")
+	// make sure mod time differs on file systems with coarse timestamps
+	future := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(certFile, future, future))
+	time.Sleep(time.Millisecond * 2)
+
+	cert, _ = store.get()
+	assert.Equal(t, second.cert.Raw, cert.Certificate[0])
+
+	// broken files don't break the store
+	assert.Nil(t, ioutil.WriteFile(certFile, []byte("broken"), 0600))
+	past := time.Now().Add(-time.Minute)
+	assert.Nil(t, os.Chtimes(certFile, past, past))
+	time.Sleep(time.Millisecond * 2)
+	cert, _ = store.get()
+	assert.Equal(t, second.cert.Raw, cert.Certificate[0])
+}
+
+func Test_TLS_WhenInvalidConfig(t *testing.T) {
+	_, err := NewServerCredentials(&TLSConfig{})
+	assert.Error(t, err)
+	_, err = NewServerCredentials(&TLSConfig{CertFile: "a", KeyFile: "b"})
+	assert.Error(t, err)
+	_, err = NewClientCredentials(&TLSConfig{CertFile: "a"})
+	assert.Error(t, err)
+}
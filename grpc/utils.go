@@ -3,13 +3,41 @@ package grpc
 import (
 	"encoding/json"
 	"github.com/exluap/kit/er"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"time"
 )
 
 // toGrpcStatus converts error (AppError) to grpc status
 func toGrpcStatus(err error) error {
 
+	// check if it's multi error, then all aggregated errors are put to details
+	if multiErr, ok := er.IsMulti(err); ok {
+
+		var grpcStatus = codes.Unknown
+		if multiErr.GrpcStatus() != nil {
+			grpcStatus = codes.Code(*multiErr.GrpcStatus())
+		}
+		st := status.New(grpcStatus, multiErr.Message())
+
+		// the first details item is the aggregate itself, others are aggregated errors
+		// RetryInfo describes the whole aggregate, retry hints of the aggregated errors are passed with their details
+		details := []proto.Message{&AppErrorDetails{Code: multiErr.Code()}}
+		for _, e := range multiErr.Errors() {
+			details = append(details, toAppErrorDetails(e, true))
+		}
+		if multiErr.Retryable() {
+			details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(multiErr.RetryAfter())})
+		}
+		st, _ = st.WithDetails(details...)
+
+		return st.Err()
+
+	}
+
 	// check if it's app error
 	if appErr, ok := er.Is(err); ok {
 
@@ -21,14 +49,14 @@ func toGrpcStatus(err error) error {
 		}
 		st := status.New(grpcStatus, appErr.Message())
 
-		// marshal fields
-		ff, _ := json.Marshal(appErr.Fields())
-
 		// put details to gRPC status
-		st, _ = st.WithDetails(&AppErrorDetails{
-			Code:   appErr.Code(),
-			Fields: ff,
-		})
+		details := []proto.Message{toAppErrorDetails(appErr, false)}
+
+		// retryable errors are marked with standard RetryInfo details
+		if appErr.Retryable() {
+			details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(appErr.RetryAfter())})
+		}
+		st, _ = st.WithDetails(details...)
 
 		return st.Err()
 
@@ -37,25 +65,96 @@ func toGrpcStatus(err error) error {
 	}
 }
 
+// reserved fields to pass message and retry hint of aggregated errors
+// the top-level message and retry hint are passed as gRPC status message and RetryInfo
+const (
+	detailsMessageField    = "_msg"
+	detailsRetryAfterField = "_retryAfter" // retry hint as duration string, present only for retryable errors
+)
+
+// toAppErrorDetails converts AppError to gRPC details message
+// withMessage specifies if message and retry hint are put to the reserved fields
+func toAppErrorDetails(appErr *er.AppError, withMessage bool) *AppErrorDetails {
+	fields := appErr.Fields()
+	if withMessage {
+		fields = make(er.FF, len(appErr.Fields())+2)
+		for k, v := range appErr.Fields() {
+			fields[k] = v
+		}
+		fields[detailsMessageField] = appErr.Message()
+		if appErr.Retryable() {
+			fields[detailsRetryAfterField] = appErr.RetryAfter().String()
+		}
+	}
+	// marshal fields
+	ff, _ := json.Marshal(fields)
+	return &AppErrorDetails{
+		Code:   appErr.Code(),
+		Fields: ff,
+	}
+}
+
 //toAppError converts gRPC status to AppError
 func toAppError(err error) error {
 
 	res := err
 
 	st := status.Convert(err)
-	details := st.Details()
-	if len(details) > 0 {
-		errDet := details[0]
-		if appErrMsg, ok := errDet.(*AppErrorDetails); ok {
-
-			var ff er.FF
-			if e := json.Unmarshal(appErrMsg.Fields, &ff); e == nil {
-				res = er.WithBuilder(appErrMsg.Code, st.Message()).F(ff).GrpcSt(uint32(st.Code())).Err()
-			}
 
+	var appErrDetails []*AppErrorDetails
+	var retryInfo *errdetails.RetryInfo
+	for _, d := range st.Details() {
+		switch det := d.(type) {
+		case *AppErrorDetails:
+			appErrDetails = append(appErrDetails, det)
+		case *errdetails.RetryInfo:
+			retryInfo = det
+		}
+	}
+
+	switch {
+	case len(appErrDetails) > 1:
+		// multiple details means multi error, the first item is the aggregate itself
+		multiErr := er.NewMulti(appErrDetails[0].Code, "%s", st.Message()).GrpcSt(uint32(st.Code()))
+		for _, d := range appErrDetails[1:] {
+			if b := fromAppErrorDetails(d, ""); b != nil {
+				multiErr.Add(b.Err())
+			}
+		}
+		res = multiErr
+	case len(appErrDetails) == 1:
+		if b := fromAppErrorDetails(appErrDetails[0], st.Message()); b != nil {
+			if retryInfo != nil {
+				b.RetryAfter(retryInfo.GetRetryDelay().AsDuration())
+			}
+			res = b.GrpcSt(uint32(st.Code())).Err()
 		}
 	}
 
 	return res
 
 }
+
+// fromAppErrorDetails converts details message to AppError builder
+// if message isn't passed, it's taken from the reserved field
+// the error is retryable if the reserved retry hint field is present
+func fromAppErrorDetails(d *AppErrorDetails, message string) er.AppErrBuilder {
+	var ff er.FF
+	if e := json.Unmarshal(d.Fields, &ff); e != nil {
+		return nil
+	}
+	if m, ok := ff[detailsMessageField].(string); ok {
+		if message == "" {
+			message = m
+		}
+		delete(ff, detailsMessageField)
+	}
+	retryAfter, retryable := ff[detailsRetryAfterField].(string)
+	delete(ff, detailsRetryAfterField)
+	b := er.WithBuilder(d.Code, "%s", message).F(ff)
+	if retryable {
+		delay, _ := time.ParseDuration(retryAfter)
+		b.RetryAfter(delay)
+	}
+	return b
+}
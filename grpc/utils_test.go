@@ -0,0 +1,35 @@
+package grpc
+
+import (
+	"github.com/exluap/kit/er"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"testing"
+	"time"
+)
+
+func Test_MultiError_RetryInfo(t *testing.T) {
+	m := er.NewMulti("ERR-200", "batch failed").GrpcSt(uint32(codes.Unavailable)).
+		Add(er.WithBuilder("ERR-201", "a").RetryAfter(time.Second).Err()).
+		Add(er.WithBuilder("ERR-202", "b").Retryable().Err())
+
+	err := toAppError(toGrpcStatus(m))
+	multi, ok := er.IsMulti(err)
+	assert.True(t, ok)
+	assert.Equal(t, 2, multi.Len())
+	assert.True(t, er.IsRetryable(err))
+	assert.Equal(t, time.Second, multi.RetryAfter())
+	assert.True(t, er.HasCode(err, "ERR-202"))
+
+	// retry hints of aggregated errors are kept as sent
+	assert.Equal(t, time.Second, multi.Errors()[0].RetryAfter())
+	assert.True(t, multi.Errors()[1].Retryable())
+	assert.Equal(t, time.Duration(0), multi.Errors()[1].RetryAfter())
+	assert.Nil(t, multi.Errors()[0].Fields()[detailsRetryAfterField])
+
+	m.Add(er.New("ERR-203", "c"))
+	multi, _ = er.IsMulti(toAppError(toGrpcStatus(m)))
+	assert.False(t, multi.Retryable())
+	assert.True(t, multi.Errors()[0].Retryable())
+	assert.False(t, multi.Errors()[2].Retryable())
+}
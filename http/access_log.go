@@ -0,0 +1,196 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	kitContext "github.com/exluap/kit/context"
+	"github.com/exluap/kit/log"
+	"github.com/exluap/kit/monitoring"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// label of requests which route has no path template
+const unknownRoute = "unknown"
+
+// serverMetrics are RED metrics of HTTP requests labelled by route template
+type serverMetrics struct {
+	requests *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+func newServerMetrics() *serverMetrics {
+	return &serverMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "kit",
+			Subsystem: "http_server",
+			Name:      "requests_total",
+			Help:      "Number of handled requests",
+		}, []string{"method", "route", "code"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "kit",
+			Subsystem: "http_server",
+			Name:      "request_errors_total",
+			Help:      "Number of requests failed with 5xx status",
+		}, []string{"method", "route", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "kit",
+			Subsystem: "http_server",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of handling requests",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+	}
+}
+
+func (m *serverMetrics) observe(method, route string, status int, d time.Duration) {
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(method, route, code).Inc()
+	if status >= http.StatusInternalServerError {
+		m.errors.WithLabelValues(method, route, code).Inc()
+	}
+	m.duration.WithLabelValues(method, route).Observe(d.Seconds())
+}
+
+// GetCollector returns request metrics, so the server can be passed to the metrics server as MetricsProvider
+// collection is empty if Metrics isn't enabled
+func (s *Server) GetCollector() monitoring.MetricsCollector {
+	return func() monitoring.MetricsCollection {
+		if s.metrics == nil {
+			return monitoring.MetricsCollection{}
+		}
+		return monitoring.MetricsCollection{s.metrics.requests, s.metrics.errors, s.metrics.duration}
+	}
+}
+
+// accessCtxKey is a context key of accessRecord
+type accessCtxKey struct{}
+
+// accessRecord keeps the latest request context, so request context built by inner middlewares gets to the access log
+type accessRecord struct {
+	ctx context.Context
+}
+
+// setAccessContext passes request context to the access log, it's called by middlewares building request context
+func setAccessContext(ctx context.Context) {
+	if rec, ok := ctx.Value(accessCtxKey{}).(*accessRecord); ok {
+		rec.ctx = ctx
+	}
+}
+
+// accessMiddleware logs every request at info level and collects request metrics
+// it wraps the whole handler, so responses of all the middlewares and of unmatched routes (404, 405) are observed
+func (s *Server) accessMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		start := time.Now()
+		rw := &statusResponseWriter{ResponseWriter: w}
+		route := s.routeTemplate(r)
+		rec := &accessRecord{ctx: r.Context()}
+
+		next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), accessCtxKey{}, rec)))
+
+		d := time.Since(start)
+		if s.metrics != nil {
+			s.metrics.observe(r.Method, route, rw.status(), d)
+		}
+		if s.cfg.AccessLog {
+			s.logger().Pr("http").Cmp("server").Mth("access").C(rec.ctx).
+				F(log.FF{
+					"method":    r.Method,
+					"route":     route,
+					"status":    rw.status(),
+					"bytes":     rw.size,
+					"ms":        d.Milliseconds(),
+					"requestId": accessRequestId(rec.ctx, r, w),
+				}).
+				Inf("access")
+		}
+	})
+}
+
+// routeTemplate returns path template of the route matching the request, so labels don't depend on path variables
+func (s *Server) routeTemplate(r *http.Request) string {
+	var match mux.RouteMatch
+	if s.RootRouter.Match(r, &match) && match.Route != nil {
+		if tpl, err := match.Route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return unknownRoute
+}
+
+// accessRequestId takes request ID from request context, if there is no context, request and response headers are checked
+func accessRequestId(ctx context.Context, r *http.Request, w http.ResponseWriter) string {
+	if rCtx, ok := kitContext.Request(ctx); ok && rCtx.GetRequestId() != "" {
+		return rCtx.GetRequestId()
+	}
+	if rid := r.Header.Get(RequestIdHeader); rid != "" {
+		return rid
+	}
+	return w.Header().Get(RequestIdHeader)
+}
+
+// statusResponseWriter captures status and size of the response
+// it passes through Flusher, Hijacker and Pusher, so streaming and websockets keep working
+type statusResponseWriter struct {
+	http.ResponseWriter
+	statusCode  int
+	size        int
+	wroteHeader bool
+}
+
+func (rw *statusResponseWriter) Write(data []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	n, err := rw.ResponseWriter.Write(data)
+	rw.size += n
+	return n, err
+}
+
+func (rw *statusResponseWriter) WriteHeader(code int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.statusCode = code
+	rw.ResponseWriter.WriteHeader(code)
+	rw.wroteHeader = true
+}
+
+func (rw *statusResponseWriter) status() int {
+	if rw.statusCode == 0 {
+		return http.StatusOK
+	}
+	return rw.statusCode
+}
+
+func (rw *statusResponseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rw *statusResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := rw.ResponseWriter.(http.Hijacker); ok {
+		c, brw, err := h.Hijack()
+		if err == nil {
+			// hijacked connections are switched protocols, e.g. websockets
+			rw.statusCode, rw.wroteHeader = http.StatusSwitchingProtocols, true
+		}
+		return c, brw, err
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (rw *statusResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := rw.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
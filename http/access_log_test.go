@@ -0,0 +1,101 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/exluap/kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_AccessLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := log.Init(&log.Config{Level: log.InfoLevel, Format: log.FormatterJson})
+	logger.Logrus.SetOutput(buf)
+
+	s := NewHttpServer(&Config{AccessLog: true}, func() log.CLogger { return log.L(logger) })
+	s.RootRouter.HandleFunc("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	})
+
+	rq := httptest.NewRequest(http.MethodPost, "/items/123", nil)
+	rq.Header.Set(RequestIdHeader, "rid-1")
+	s.Srv.Handler.ServeHTTP(httptest.NewRecorder(), rq)
+
+	entry := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "access", entry["msg"])
+	assert.Equal(t, "info", entry["level"])
+	assert.Equal(t, "POST", entry["method"])
+	assert.Equal(t, "/items/{id}", entry["route"])
+	assert.Equal(t, float64(http.StatusCreated), entry["status"])
+	assert.Equal(t, float64(5), entry["bytes"])
+	assert.Equal(t, "rid-1", entry["requestId"])
+	assert.Contains(t, entry, "ms")
+}
+
+func Test_AccessLog_OutsideRouter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := log.Init(&log.Config{Level: log.InfoLevel, Format: log.FormatterJson})
+	logger.Logrus.SetOutput(buf)
+
+	s := NewHttpServer(&Config{AccessLog: true}, func() log.CLogger { return log.L(logger) })
+	s.RootRouter.HandleFunc("/items/{id}", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodGet)
+	assert.Nil(t, s.UseRequestContext(&RequestContextConfig{Jwt: &JwtConfig{Keys: []*JwtKey{{Alg: JwtAlgHS256, Secret: "secret"}}}, AuthRequired: true}))
+
+	access := func(method, url string) map[string]interface{} {
+		buf.Reset()
+		rs := httptest.NewRecorder()
+		s.Srv.Handler.ServeHTTP(rs, httptest.NewRequest(method, url, nil))
+		entry := map[string]interface{}{}
+		// the last entry is the access log
+		lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+		assert.Nil(t, json.Unmarshal(lines[len(lines)-1], &entry))
+		assert.Equal(t, "access", entry["msg"])
+		// request ID is taken from request context built by the middleware
+		assert.Equal(t, rs.Header().Get(RequestIdHeader), entry["requestId"])
+		return entry
+	}
+
+	// rejected by request context middleware
+	entry := access(http.MethodGet, "/items/1")
+	assert.Equal(t, float64(http.StatusUnauthorized), entry["status"])
+	assert.Equal(t, "/items/{id}", entry["route"])
+
+	// not matched by router
+	s.Srv.Handler = s.rootHandler(s.RootRouter)
+	entry = access(http.MethodGet, "/unknown")
+	assert.Equal(t, float64(http.StatusNotFound), entry["status"])
+	assert.Equal(t, unknownRoute, entry["route"])
+	entry = access(http.MethodPost, "/items/1")
+	assert.Equal(t, float64(http.StatusMethodNotAllowed), entry["status"])
+	assert.Equal(t, unknownRoute, entry["route"])
+}
+
+func Test_Metrics(t *testing.T) {
+	s := NewHttpServer(&Config{Metrics: true}, testLogger())
+	s.RootRouter.HandleFunc("/items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	})
+
+	for _, url := range []string{"/items/1", "/items/2", "/items/3?fail=1"} {
+		s.Srv.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, url, nil))
+	}
+
+	assert.Len(t, s.GetCollector()(), 3)
+	assert.Equal(t, float64(2), testutil.ToFloat64(s.metrics.requests.WithLabelValues("GET", "/items/{id}", "200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.metrics.requests.WithLabelValues("GET", "/items/{id}", "500")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(s.metrics.errors.WithLabelValues("GET", "/items/{id}", "500")))
+	assert.Equal(t, 1, testutil.CollectAndCount(s.metrics.duration))
+}
+
+func Test_Metrics_Disabled(t *testing.T) {
+	s := NewHttpServer(&Config{}, testLogger())
+	assert.Empty(t, s.GetCollector()())
+}
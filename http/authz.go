@@ -0,0 +1,59 @@
+package http
+
+import (
+	"github.com/exluap/kit/auth"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// RequireRoles creates a middleware which rejects with 403 callers not having all the roles
+// roles are taken from the request context, so it must be executed after request context middleware
+func (c *BaseController) RequireRoles(roles ...string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := auth.RequireRoles(r.Context(), routeResource(r), roles...); err != nil {
+				c.RespondErrorWithRequest(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireAnyRole creates a middleware which rejects with 403 callers not having any of the roles
+func (c *BaseController) RequireAnyRole(roles ...string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := auth.RequireAnyRole(r.Context(), routeResource(r), roles...); err != nil {
+				c.RespondErrorWithRequest(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePolicy creates a middleware which checks access according to the policy
+// resource of the rule is "METHOD path-template" of the matched route (e.g. GET /api/users/{id})
+func (c *BaseController) RequirePolicy(policy *auth.Policy) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := policy.Check(r.Context(), routeResource(r)); err != nil {
+				c.RespondErrorWithRequest(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeResource returns "METHOD path-template" of the matched route, if there is no matched route, URL path is taken
+func routeResource(r *http.Request) string {
+	path := r.URL.Path
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			path = tpl
+		}
+	}
+	return r.Method + " " + path
+}
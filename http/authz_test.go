@@ -0,0 +1,53 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/exluap/kit/auth"
+	kitContext "github.com/exluap/kit/context"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_RequirePolicy(t *testing.T) {
+	c := &BaseController{}
+	policy, err := auth.NewPolicy(&auth.PolicyConfig{Rules: []*auth.Rule{{Resource: "GET /users/{id}", AnyOf: []string{"admin"}}}})
+	assert.Nil(t, err)
+
+	r := mux.NewRouter()
+	r.Use(c.RequirePolicy(policy))
+	r.HandleFunc("/users/{id}", func(w http.ResponseWriter, r *http.Request) { c.RespondOK(w, EmptyOkResponse) }).Methods(http.MethodGet)
+
+	call := func(roles ...string) *httptest.ResponseRecorder {
+		ctx := kitContext.NewRequestCtx().Rest().WithRoles(roles...).ToContext(context.Background())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil).WithContext(ctx))
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, call("admin").Code)
+	w := call("user")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var rs Error
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &rs))
+	assert.Equal(t, auth.ErrCodeAuthAccessDenied, rs.Code)
+	assert.Equal(t, "GET /users/{id}", rs.Details["resource"])
+}
+
+func Test_RequireRoles(t *testing.T) {
+	c := &BaseController{}
+	h := c.RequireRoles("a", "b")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	ctx := kitContext.NewRequestCtx().Rest().WithRoles("a").ToContext(context.Background())
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	h = c.RequireAnyRole("a", "b")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,354 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	kitContext "github.com/exluap/kit/context"
+	"github.com/exluap/kit/er"
+	"github.com/exluap/kit/log"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ClientTimeout         = time.Second * 30
+	ClientRetryBackoff    = time.Millisecond * 100
+	ClientMaxRetryBackoff = time.Second * 5
+
+	maxErrorBodySize   = 1 << 20
+	maxErrorBodyLogged = 1024
+)
+
+// ClientConfig is HTTP client configuration
+type ClientConfig struct {
+	// Timeout - overall timeout of the request including retries, 30s by default
+	Timeout time.Duration
+	// Retries - max number of retries, requests are retried only if it's safe (idempotent method and replayable body)
+	Retries int
+	// RetryBackoff - initial backoff between retries, it's doubled each retry, 100ms by default
+	RetryBackoff time.Duration `config:"retry-backoff"`
+	// MaxRetryBackoff - max backoff between retries, 5s by default
+	MaxRetryBackoff time.Duration `config:"max-retry-backoff"`
+	// Trace - if true, requests and responses are logged
+	Trace bool
+	// Tracing - configuration of request/response tracing enabled by Trace
+	Tracing *TraceConfig
+	// PropagateContext - if true, the whole request context (user, roles, session, baggage, locale) is passed to the callee
+	// otherwise only request ID and trace context are passed, enable it only for calls to kit services
+	PropagateContext bool `config:"propagate-context"`
+	// TrustedHosts - if specified, the whole request context is passed only to these hosts (host or host:port)
+	TrustedHosts []string `config:"trusted-hosts"`
+}
+
+// Client is HTTP client propagating request context
+type Client struct {
+	*http.Client
+}
+
+// NewClient creates HTTP client
+// request context is propagated to the callee, failed requests are retried with backoff
+// non-2xx responses are converted to AppError
+func NewClient(cfg *ClientConfig, logger log.CLoggerFunc) *Client {
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = ClientTimeout
+	}
+	return &Client{
+		Client: &http.Client{
+			Timeout:   timeout,
+			Transport: NewTransport(http.DefaultTransport, cfg, logger),
+		},
+	}
+}
+
+// Do sends request and converts non-2xx response to AppError
+// if error is returned, response body is already closed
+func (c *Client) Do(r *http.Request) (*http.Response, error) {
+	rs, err := c.Client.Do(r)
+	if err != nil {
+		return nil, ErrHttpClientRequest(err, r.Context())
+	}
+	if err := ErrorFromResponse(rs); err != nil {
+		_ = rs.Body.Close()
+		return nil, err
+	}
+	return rs, nil
+}
+
+// DoJson sends rq as JSON body and decodes JSON response to rs, both rq and rs can be nil
+func (c *Client) DoJson(ctx context.Context, method, url string, rq, rs interface{}) error {
+	var body io.Reader
+	if rq != nil {
+		data, err := json.Marshal(rq)
+		if err != nil {
+			return ErrHttpClientRequest(err, ctx)
+		}
+		body = bytes.NewReader(data)
+	}
+	r, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return ErrHttpClientRequest(err, ctx)
+	}
+	if rq != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	r.Header.Set("Accept", "application/json")
+
+	resp, err := c.Do(r)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if rs != nil {
+		if err := json.NewDecoder(resp.Body).Decode(rs); err != nil {
+			return ErrHttpClientDecodeResponse(err, ctx)
+		}
+	}
+	return nil
+}
+
+// Transport is a RoundTripper propagating request context and retrying failed requests
+type Transport struct {
+	base   http.RoundTripper
+	cfg    *ClientConfig
+	logger log.CLoggerFunc
+	tracer *tracer
+}
+
+// NewTransport wraps base RoundTripper, use it if you need a custom http.Client
+func NewTransport(base http.RoundTripper, cfg *ClientConfig, logger log.CLoggerFunc) *Transport {
+	return &Transport{base: base, cfg: cfg, logger: logger, tracer: newTracer(cfg.Tracing)}
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+
+	// RoundTripper mustn't modify the original request
+	r = r.Clone(r.Context())
+	t.setContextHeaders(r)
+
+	backoff := t.cfg.RetryBackoff
+	if backoff == 0 {
+		backoff = ClientRetryBackoff
+	}
+	maxBackoff := t.cfg.MaxRetryBackoff
+	if maxBackoff == 0 {
+		maxBackoff = ClientMaxRetryBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+
+		t.logRequest(r)
+		rs, err := t.base.RoundTrip(r)
+		if err == nil {
+			t.logResponse(r, rs)
+		}
+
+		if attempt >= t.cfg.Retries || !shouldRetry(r, rs, err) {
+			return rs, err
+		}
+
+		// the server might say when to retry
+		wait := backoff
+		if rs != nil {
+			if ra := retryAfter(rs); ra > 0 {
+				wait = ra
+			}
+			_, _ = io.Copy(ioutil.Discard, io.LimitReader(rs.Body, maxErrorBodySize))
+			_ = rs.Body.Close()
+		}
+		if wait > maxBackoff {
+			wait = maxBackoff
+		}
+		if t.logger != nil {
+			t.logger().Cmp("client").C(r.Context()).F(log.FF{"method": r.Method, "URL": r.URL.String(), "attempt": attempt + 1, "wait": wait}).Dbg("retrying")
+		}
+
+		select {
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		case <-time.After(wait):
+		}
+
+		if r.Body != nil && r.GetBody != nil {
+			body, err := r.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (t *Transport) logRequest(r *http.Request) {
+	if !t.cfg.Trace || t.logger == nil {
+		return
+	}
+	body := "[skipped]"
+	if t.tracer.loggable(r.Header.Get("Content-Type")) {
+		var head []byte
+		if r.Body != nil && r.GetBody != nil {
+			if rc, err := r.GetBody(); err == nil {
+				// only head of the body is read
+				head, _ = ioutil.ReadAll(io.LimitReader(rc, int64(t.tracer.maxBodyBytes+1)))
+				_ = rc.Close()
+			}
+		}
+		body = t.tracer.body(head, r.Header.Get("Content-Type"))
+	}
+	t.logger().Cmp("client").C(r.Context()).F(log.FF{"method": r.Method, "URL": r.URL.String(), "headers": t.tracer.headers(r.Header), "body": body}).Trc("request")
+}
+
+func (t *Transport) logResponse(r *http.Request, rs *http.Response) {
+	if !t.cfg.Trace || t.logger == nil {
+		return
+	}
+	body := "[skipped]"
+	if t.tracer.loggable(rs.Header.Get("Content-Type")) {
+		// only head of the body is read, the rest is left for the caller
+		var head []byte
+		head, rs.Body = t.tracer.peekBody(rs.Body)
+		body = t.tracer.body(head, rs.Header.Get("Content-Type"))
+	}
+	t.logger().Cmp("client").C(r.Context()).F(log.FF{"status": rs.StatusCode, "headers": t.tracer.headers(rs.Header), "body": body}).Trc("response")
+}
+
+// setContextHeaders puts request context to request headers, headers set by the caller aren't overridden
+// request ID and trace context are always passed, the rest only if propagation is enabled for the host
+func (t *Transport) setContextHeaders(r *http.Request) {
+	rCtx, ok := kitContext.Request(r.Context())
+	if !ok {
+		return
+	}
+	rCtx = rCtx.ChildSpan()
+
+	set := func(header, value string) {
+		if value != "" && r.Header.Get(header) == "" {
+			r.Header.Set(header, value)
+		}
+	}
+	set(RequestIdHeader, rCtx.GetRequestId())
+	set(kitContext.TraceparentHeader, rCtx.Traceparent())
+	if !t.propagateTo(r.URL) {
+		return
+	}
+	set(SessionIdHeader, rCtx.GetSessionId())
+	set(kitContext.BaggageHeader, rCtx.BaggageValue())
+	if len(rCtx.GetLang()) > 0 {
+		set("Accept-Language", strings.ReplaceAll(strings.Join(rCtx.GetLang(), ","), "_", "-"))
+	}
+	set(ContextHeader, base64.RawURLEncoding.EncodeToString(kitContext.MarshalRequestContext(rCtx)))
+}
+
+// propagateTo checks if the whole request context is passed to the host
+func (t *Transport) propagateTo(u *url.URL) bool {
+	if !t.cfg.PropagateContext {
+		return false
+	}
+	if len(t.cfg.TrustedHosts) == 0 {
+		return true
+	}
+	for _, h := range t.cfg.TrustedHosts {
+		if strings.EqualFold(h, u.Host) || strings.EqualFold(h, u.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldRetry checks if request failed with transient error and it's safe to retry it
+func shouldRetry(r *http.Request, rs *http.Response, err error) bool {
+	if r.Context().Err() != nil {
+		return false
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		return false
+	}
+	if r.Body != nil && r.Body != http.NoBody && r.GetBody == nil {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	switch rs.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter takes Retry-After header in seconds
+func retryAfter(rs *http.Response) time.Duration {
+	if s, err := strconv.Atoi(rs.Header.Get("Retry-After")); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return 0
+}
+
+// ErrorFromResponse converts non-2xx response to AppError
+// if the response carries kit Error or RFC 7807 problem, its code and details are preserved
+// response body is consumed in case of error
+func ErrorFromResponse(rs *http.Response) error {
+	if rs.StatusCode >= 200 && rs.StatusCode < 300 {
+		return nil
+	}
+
+	ctx := context.Background()
+	if rs.Request != nil {
+		ctx = rs.Request.Context()
+	}
+
+	body, _ := ioutil.ReadAll(io.LimitReader(rs.Body, maxErrorBodySize))
+
+	var b er.AppErrBuilder
+	if strings.HasPrefix(rs.Header.Get("Content-Type"), problemContentType) {
+		var problem map[string]interface{}
+		if json.Unmarshal(body, &problem) == nil {
+			if code, _ := problem["code"].(string); code != "" {
+				detail, _ := problem["detail"].(string)
+				fields := er.FF{}
+				for k, v := range problem {
+					switch k {
+					case "type", "title", "status", "detail", "instance", "code", "translationKey", "retryable":
+					default:
+						fields[k] = v
+					}
+				}
+				b = er.WithBuilder(code, "%s", detail).F(fields)
+				if retryable, _ := problem["retryable"].(bool); retryable {
+					b.Retryable()
+				}
+			}
+		}
+	} else {
+		var httpErr Error
+		if json.Unmarshal(body, &httpErr) == nil && httpErr.Code != "" {
+			b = er.WithBuilder(httpErr.Code, "%s", httpErr.Message).F(httpErr.Details)
+			if httpErr.Retryable {
+				b.Retryable()
+			}
+		}
+	}
+
+	if b == nil {
+		if len(body) > maxErrorBodyLogged {
+			body = body[:maxErrorBodyLogged]
+		}
+		return ErrHttpClientResponse(ctx, rs.StatusCode, string(body))
+	}
+	if ra := retryAfter(rs); ra > 0 {
+		b.RetryAfter(ra)
+	}
+	return b.HttpSt(uint32(rs.StatusCode)).C(ctx).Err()
+}
@@ -0,0 +1,125 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	kitContext "github.com/exluap/kit/context"
+	"github.com/exluap/kit/er"
+	"github.com/exluap/kit/log"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Client_PropagatesContext(t *testing.T) {
+	mdw, err := NewRequestContextMiddleware(&RequestContextConfig{TrustContextHeader: true}, nil, nil)
+	assert.Nil(t, err)
+	var rCtx *kitContext.RequestContext
+	srv := httptest.NewServer(mdw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rCtx, _ = kitContext.Request(r.Context())
+		(&BaseController{}).RespondOK(w, EmptyOkResponse)
+	})))
+	defer srv.Close()
+
+	parent := kitContext.NewRequestCtx().Rest().WithRequestId("rid-1").WithSessionId("sid-1").WithUser("uid", "un").WithRoles("admin").WithNewTrace()
+	var rs struct{ Status string }
+	err = NewClient(&ClientConfig{PropagateContext: true}, nil).DoJson(parent.ToContext(context.Background()), http.MethodGet, srv.URL, nil, &rs)
+	assert.Nil(t, err)
+	assert.Equal(t, "OK", rs.Status)
+	assert.Equal(t, "rid-1", rCtx.GetRequestId())
+	assert.Equal(t, "sid-1", rCtx.GetSessionId())
+	assert.Equal(t, "uid", rCtx.GetUserId())
+	assert.Equal(t, []string{"admin"}, rCtx.GetRoles())
+	assert.Equal(t, parent.GetTraceId(), rCtx.GetTraceId())
+}
+
+func Test_Client_PropagatesOnlyTraceByDefault(t *testing.T) {
+	var headers http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+	}))
+	defer srv.Close()
+
+	ctx := kitContext.NewRequestCtx().Rest().WithRequestId("rid-1").WithSessionId("sid-1").WithUser("uid", "un").WithLang("ru").WithNewTrace().ToContext(context.Background())
+	for _, cfg := range []*ClientConfig{{}, {PropagateContext: true, TrustedHosts: []string{"kit.local"}}} {
+		assert.Nil(t, NewClient(cfg, nil).DoJson(ctx, http.MethodGet, srv.URL, nil, nil))
+		assert.Equal(t, "rid-1", headers.Get(RequestIdHeader))
+		assert.NotEmpty(t, headers.Get(kitContext.TraceparentHeader))
+		assert.Empty(t, headers.Get(ContextHeader))
+		assert.Empty(t, headers.Get(SessionIdHeader))
+		assert.Empty(t, headers.Get("Accept-Language"))
+	}
+
+	// trusted host gets the whole context
+	assert.Nil(t, NewClient(&ClientConfig{PropagateContext: true, TrustedHosts: []string{"127.0.0.1"}}, nil).DoJson(ctx, http.MethodGet, srv.URL, nil, nil))
+	assert.NotEmpty(t, headers.Get(ContextHeader))
+	assert.Equal(t, "sid-1", headers.Get(SessionIdHeader))
+}
+
+func Test_Client_Retries(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cl := NewClient(&ClientConfig{Retries: 2, RetryBackoff: time.Millisecond}, nil)
+	assert.Nil(t, cl.DoJson(context.Background(), http.MethodGet, srv.URL, nil, nil))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	// non-idempotent requests aren't retried
+	atomic.StoreInt32(&calls, 0)
+	err := cl.DoJson(context.Background(), http.MethodPost, srv.URL, map[string]string{"a": "b"}, nil)
+	assert.True(t, er.HasCode(err, ErrCodeHttpClientResponse))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func Test_Client_ConvertsKitError(t *testing.T) {
+	for _, format := range []string{ErrorFormatKit, ErrorFormatProblem} {
+		c := &BaseController{ErrorRenderer: NewErrorRenderer(&Config{ErrorFormat: format})}
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c.RespondErrorWithRequest(w, r, er.WithBuilder("TST-CL-001", "not found").F(er.FF{"id": "1"}).HttpSt(http.StatusNotFound).Err())
+		}))
+
+		err := NewClient(&ClientConfig{}, nil).DoJson(context.Background(), http.MethodGet, srv.URL, nil, nil)
+		appErr, ok := er.Is(err)
+		assert.True(t, ok, format)
+		assert.Equal(t, "TST-CL-001", appErr.Code(), format)
+		assert.Equal(t, "not found", appErr.Message(), format)
+		assert.Equal(t, "1", appErr.Fields()["id"], format)
+		assert.Equal(t, uint32(http.StatusNotFound), *appErr.HttpStatus(), format)
+		srv.Close()
+	}
+}
+
+func Test_Client_TraceCapsBody(t *testing.T) {
+	big := strings.Repeat("a", 100)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(big))
+	}))
+	defer srv.Close()
+
+	buf := &bytes.Buffer{}
+	logger := log.Init(&log.Config{Level: log.TraceLevel, Format: log.FormatterJson})
+	logger.Logrus.SetOutput(buf)
+	cl := NewClient(&ClientConfig{Trace: true, Tracing: &TraceConfig{MaxBodyBytes: 10}}, func() log.CLogger { return log.L(logger) })
+
+	rs, err := cl.Get(srv.URL)
+	assert.Nil(t, err)
+	defer rs.Body.Close()
+	body, _ := ioutil.ReadAll(rs.Body)
+	// the caller gets the whole body, the log gets its head
+	assert.Equal(t, big, string(body))
+	assert.Contains(t, buf.String(), "aaaaaaaaaa...[truncated, more than 10 bytes]")
+	assert.NotContains(t, buf.String(), big)
+}
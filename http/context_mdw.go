@@ -0,0 +1,213 @@
+package http
+
+import (
+	"encoding/base64"
+	"errors"
+	kitContext "github.com/exluap/kit/context"
+	"github.com/exluap/kit/log"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strings"
+)
+
+const (
+	RequestIdHeader = "X-Request-Id" // RequestIdHeader default request ID header
+	SessionIdHeader = "X-Session-Id" // SessionIdHeader default session ID header
+	// ContextHeader passes the whole request context between kit services
+	// it's taken by the request context middleware only if it's explicitly trusted
+	ContextHeader = "X-Kit-Context"
+
+	maxRequestIdLen = 128
+)
+
+// RequestContextConfig configures middleware building request context
+type RequestContextConfig struct {
+	RequestIdHeader string `config:"request-id-header"` // RequestIdHeader - request ID header, X-Request-Id by default
+	SessionIdHeader string `config:"session-id-header"` // SessionIdHeader - session ID header, X-Session-Id by default
+	// TrustContextHeader - if true, request context passed by kit HTTP client is taken
+	// enable it only for services which aren't exposed to untrusted callers
+	TrustContextHeader bool `config:"trust-context-header"`
+	// Jwt - JWT validation, if not specified, bearer token isn't checked
+	Jwt *JwtConfig
+	// AuthRequired - if true, requests without bearer token are rejected
+	AuthRequired bool `config:"auth-required"`
+	// UserIdClaim - claim mapped to user ID, "sub" by default
+	UserIdClaim string `config:"user-id-claim"`
+	// UsernameClaim - claim mapped to username, "preferred_username" by default
+	UsernameClaim string `config:"username-claim"`
+	// RolesClaim - claim mapped to roles, "roles" by default, nested claims are separated by dot (e.g. realm_access.roles)
+	RolesClaim string `config:"roles-claim"`
+	// SessionIdClaim - claim mapped to session ID if there is no session ID header, "sid" by default
+	SessionIdClaim string `config:"session-id-claim"`
+}
+
+func (c *RequestContextConfig) withDefaults() *RequestContextConfig {
+	res := *c
+	if res.RequestIdHeader == "" {
+		res.RequestIdHeader = RequestIdHeader
+	}
+	if res.SessionIdHeader == "" {
+		res.SessionIdHeader = SessionIdHeader
+	}
+	if res.UserIdClaim == "" {
+		res.UserIdClaim = "sub"
+	}
+	if res.UsernameClaim == "" {
+		res.UsernameClaim = "preferred_username"
+	}
+	if res.RolesClaim == "" {
+		res.RolesClaim = "roles"
+	}
+	if res.SessionIdClaim == "" {
+		res.SessionIdClaim = "sid"
+	}
+	return &res
+}
+
+// requestContextMiddleware builds request context from incoming headers and bearer token
+type requestContextMiddleware struct {
+	cfg      *RequestContextConfig
+	verifier *jwtVerifier
+	ctrl     *BaseController
+}
+
+// NewRequestContextMiddleware creates a middleware building request context from incoming headers and bearer JWT
+// request ID is taken from the header or generated and it's echoed in the response
+// invalid tokens are rejected with 401
+func NewRequestContextMiddleware(cfg *RequestContextConfig, logger log.CLoggerFunc, renderer ErrorRenderer) (mux.MiddlewareFunc, error) {
+	m := &requestContextMiddleware{
+		cfg:  cfg.withDefaults(),
+		ctrl: &BaseController{Logger: logger, ErrorRenderer: renderer},
+	}
+	if cfg.AuthRequired && cfg.Jwt == nil {
+		// without verifier every request would pass unauthenticated
+		return nil, ErrHttpAuthConfigInvalid(errors.New("auth is required, but JWT isn't configured"))
+	}
+	if cfg.Jwt != nil {
+		v, err := newJwtVerifier(cfg.Jwt)
+		if err != nil {
+			return nil, ErrHttpAuthConfigInvalid(err)
+		}
+		m.verifier = v
+	}
+	return m.middleware, nil
+}
+
+// UseRequestContext sets up middleware building request context for all the requests
+// it's executed before any router middleware, so request context is available in logs
+func (s *Server) UseRequestContext(cfg *RequestContextConfig) error {
+	mdw, err := NewRequestContextMiddleware(cfg, s.logger, s.ErrorRenderer)
+	if err != nil {
+		return err
+	}
+	s.Srv.Handler = s.rootHandler(mdw(s.RootRouter))
+	return nil
+}
+
+func (m *requestContextMiddleware) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		rCtx := kitContext.NewRequestCtx()
+		if m.cfg.TrustContextHeader {
+			rCtx = m.contextFromHeader(r)
+		}
+		rCtx.Rest()
+
+		// take request ID from the caller if it looks sane, otherwise generate a new one
+		// values taken from the context header are overridden only by headers actually passed
+		if rid := r.Header.Get(m.cfg.RequestIdHeader); isValidRequestId(rid) {
+			rCtx.WithRequestId(rid)
+		} else if rCtx.GetRequestId() == "" {
+			rCtx.WithNewRequestId()
+		}
+		w.Header().Set(m.cfg.RequestIdHeader, rCtx.GetRequestId())
+
+		if sid := r.Header.Get(m.cfg.SessionIdHeader); sid != "" {
+			rCtx.WithSessionId(sid)
+		}
+
+		if _, err := rCtx.WithTraceparent(r.Header.Get(kitContext.TraceparentHeader)); err != nil && rCtx.Traceparent() == "" {
+			rCtx.WithNewTrace()
+		}
+		if baggage := r.Header.Get(kitContext.BaggageHeader); baggage != "" {
+			rCtx.WithBaggageValue(baggage)
+		}
+		if lang := ParseAcceptLanguage(r.Header.Get("Accept-Language")); len(lang) > 0 {
+			rCtx.WithLang(lang...)
+		}
+
+		r = r.WithContext(rCtx.ToContext(r.Context()))
+		setAccessContext(r.Context())
+
+		if m.verifier != nil {
+			if err := m.authenticate(r, rCtx); err != nil {
+				m.ctrl.RespondErrorWithRequest(w, r, err)
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// contextFromHeader takes request context passed by kit HTTP client
+// the header is decoded in lenient mode, so if it's malformed, an empty context is used
+func (m *requestContextMiddleware) contextFromHeader(r *http.Request) *kitContext.RequestContext {
+	if h := r.Header.Get(ContextHeader); h != "" {
+		if data, err := base64.RawURLEncoding.DecodeString(h); err == nil {
+			if rCtx, err := kitContext.UnmarshalRequestContext(data, kitContext.DecodeLenient); err == nil {
+				return rCtx
+			}
+		}
+	}
+	return kitContext.NewRequestCtx()
+}
+
+// authenticate validates bearer token and maps its claims to the request context
+func (m *requestContextMiddleware) authenticate(r *http.Request, rCtx *kitContext.RequestContext) error {
+
+	token := bearerToken(r)
+	if token == "" {
+		if m.cfg.AuthRequired {
+			return ErrHttpAuthTokenMissing(r.Context())
+		}
+		return nil
+	}
+
+	claims, err := m.verifier.verify(token)
+	if err != nil {
+		return ErrHttpAuthTokenInvalid(err, r.Context())
+	}
+
+	uid, _ := claimValue(claims, m.cfg.UserIdClaim).(string)
+	un, _ := claimValue(claims, m.cfg.UsernameClaim).(string)
+	rCtx.WithUser(uid, un)
+	rCtx.WithRoles(claimStrings(claimValue(claims, m.cfg.RolesClaim))...)
+	if rCtx.GetSessionId() == "" {
+		sid, _ := claimValue(claims, m.cfg.SessionIdClaim).(string)
+		rCtx.WithSessionId(sid)
+	}
+	return nil
+}
+
+// bearerToken extracts bearer token from Authorization header
+func bearerToken(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// isValidRequestId checks request ID passed by the caller, it mustn't be too long or contain unsafe chars
+func isValidRequestId(rid string) bool {
+	if rid == "" || len(rid) > maxRequestIdLen {
+		return false
+	}
+	for _, c := range rid {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:", c)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,194 @@
+package http
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	kitContext "github.com/exluap/kit/context"
+	"github.com/exluap/kit/er"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func testToken(t *testing.T, alg string, sign func([]byte) []byte, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	assert.Nil(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+func hs256(secret string) func([]byte) []byte {
+	return func(data []byte) []byte {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(data)
+		return mac.Sum(nil)
+	}
+}
+
+// serve executes request through the middleware and returns request context passed to the handler
+func serve(t *testing.T, cfg *RequestContextConfig, r *http.Request) (*httptest.ResponseRecorder, *kitContext.RequestContext) {
+	mdw, err := NewRequestContextMiddleware(cfg, nil, nil)
+	assert.Nil(t, err)
+	var rCtx *kitContext.RequestContext
+	w := httptest.NewRecorder()
+	mdw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rCtx, _ = kitContext.Request(r.Context())
+	})).ServeHTTP(w, r)
+	return w, rCtx
+}
+
+func Test_RequestContextMdw_Headers(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIdHeader, "rid-1")
+	r.Header.Set(SessionIdHeader, "sid-1")
+	r.Header.Set("Accept-Language", "ru-RU,en;q=0.5")
+	r.Header.Set(kitContext.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.Header.Set(kitContext.BaggageHeader, "tenant=t1")
+	w, rCtx := serve(t, &RequestContextConfig{}, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "rid-1", w.Header().Get(RequestIdHeader))
+	assert.Equal(t, "rid-1", rCtx.GetRequestId())
+	assert.Equal(t, "sid-1", rCtx.GetSessionId())
+	assert.Equal(t, kitContext.CallerTypeRest, rCtx.GetCaller())
+	assert.Equal(t, []string{"ru_RU", "ru", "en"}, rCtx.GetLang())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", rCtx.GetTraceId())
+	assert.Equal(t, "00f067aa0ba902b7", rCtx.GetParentSpanId())
+	assert.Equal(t, "t1", rCtx.GetBaggage()["tenant"])
+}
+
+func Test_RequestContextMdw_WhenInvalidRequestId(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIdHeader, "bad\nid")
+	w, rCtx := serve(t, &RequestContextConfig{}, r)
+	assert.NotEmpty(t, rCtx.GetRequestId())
+	assert.NotEqual(t, "bad\nid", rCtx.GetRequestId())
+	assert.Equal(t, rCtx.GetRequestId(), w.Header().Get(RequestIdHeader))
+	assert.NotEmpty(t, rCtx.GetTraceId())
+}
+
+func Test_RequestContextMdw_ContextHeader(t *testing.T) {
+	passed := kitContext.NewRequestCtx().WithRequestId("rid-1").WithSessionId("sid-1").WithNewTrace()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(ContextHeader, base64.RawURLEncoding.EncodeToString(kitContext.MarshalRequestContext(passed)))
+
+	// values of the context header are kept if there are no headers overriding them
+	_, rCtx := serve(t, &RequestContextConfig{TrustContextHeader: true}, r)
+	assert.Equal(t, "rid-1", rCtx.GetRequestId())
+	assert.Equal(t, "sid-1", rCtx.GetSessionId())
+	assert.Equal(t, passed.GetTraceId(), rCtx.GetTraceId())
+
+	r.Header.Set(SessionIdHeader, "sid-2")
+	r.Header.Set(kitContext.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, rCtx = serve(t, &RequestContextConfig{TrustContextHeader: true}, r)
+	assert.Equal(t, "sid-2", rCtx.GetSessionId())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", rCtx.GetTraceId())
+}
+
+func Test_RequestContextMdw_HS256(t *testing.T) {
+	cfg := &RequestContextConfig{
+		Jwt:        &JwtConfig{Keys: []*JwtKey{{Alg: JwtAlgHS256, Secret: "secret"}}, Issuer: "kit"},
+		RolesClaim: "realm_access.roles",
+	}
+	token := testToken(t, JwtAlgHS256, hs256("secret"), map[string]interface{}{
+		"sub":                "user-1",
+		"preferred_username": "john",
+		"sid":                "sid-1",
+		"iss":                "kit",
+		"exp":                time.Now().Add(time.Minute).Unix(),
+		"realm_access":       map[string]interface{}{"roles": []string{"admin", "user"}},
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w, rCtx := serve(t, cfg, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user-1", rCtx.GetUserId())
+	assert.Equal(t, "john", rCtx.GetUsername())
+	assert.Equal(t, "sid-1", rCtx.GetSessionId())
+	assert.Equal(t, []string{"admin", "user"}, rCtx.GetRoles())
+}
+
+func Test_RequestContextMdw_RS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.Nil(t, err)
+	cfg := &RequestContextConfig{
+		Jwt: &JwtConfig{Keys: []*JwtKey{{Alg: JwtAlgRS256, PublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))}}},
+	}
+	token := testToken(t, JwtAlgRS256, func(data []byte) []byte {
+		h := sha256.Sum256(data)
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, h[:])
+		assert.Nil(t, err)
+		return sig
+	}, map[string]interface{}{"sub": "user-1", "preferred_username": "john", "roles": "admin user", "exp": time.Now().Add(time.Minute).Unix()})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	w, rCtx := serve(t, cfg, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user-1", rCtx.GetUserId())
+	assert.Equal(t, []string{"admin", "user"}, rCtx.GetRoles())
+}
+
+func Test_RequestContextMdw_WhenInvalidToken(t *testing.T) {
+	cfg := &RequestContextConfig{Jwt: &JwtConfig{Keys: []*JwtKey{{Alg: JwtAlgHS256, Secret: "secret"}}}}
+	for name, token := range map[string]string{
+		"wrong secret": testToken(t, JwtAlgHS256, hs256("other"), map[string]interface{}{"sub": "user-1"}),
+		"no exp":       testToken(t, JwtAlgHS256, hs256("secret"), map[string]interface{}{"sub": "user-1"}),
+		"expired":      testToken(t, JwtAlgHS256, hs256("secret"), map[string]interface{}{"sub": "user-1", "exp": time.Now().Add(-time.Minute).Unix()}),
+		"alg none":     testToken(t, "none", func([]byte) []byte { return nil }, map[string]interface{}{"sub": "user-1"}),
+		"malformed":    "abc",
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w, rCtx := serve(t, cfg, r)
+		assert.Equal(t, http.StatusUnauthorized, w.Code, name)
+		assert.Nil(t, rCtx, name)
+		var rs Error
+		assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &rs), name)
+		assert.Equal(t, ErrCodeHttpAuthTokenInvalid, rs.Code, name)
+		assert.NotEmpty(t, w.Header().Get(RequestIdHeader), name)
+	}
+}
+
+func Test_RequestContextMdw_WhenTokenMissing(t *testing.T) {
+	cfg := &RequestContextConfig{Jwt: &JwtConfig{Keys: []*JwtKey{{Alg: JwtAlgHS256, Secret: "secret"}}}}
+
+	_, rCtx := serve(t, cfg, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.NotNil(t, rCtx)
+	assert.Empty(t, rCtx.GetUserId())
+
+	cfg.AuthRequired = true
+	w, _ := serve(t, cfg, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func Test_RequestContextMdw_WhenInvalidConfig(t *testing.T) {
+	_, err := NewRequestContextMiddleware(&RequestContextConfig{Jwt: &JwtConfig{Keys: []*JwtKey{{Alg: JwtAlgRS256, PublicKey: "bad"}}}}, nil, nil)
+	assert.Error(t, err)
+	_, err = NewRequestContextMiddleware(&RequestContextConfig{Jwt: &JwtConfig{}}, nil, nil)
+	assert.Error(t, err)
+	_, err = NewRequestContextMiddleware(&RequestContextConfig{AuthRequired: true}, nil, nil)
+	assert.True(t, er.HasCode(err, ErrCodeHttpAuthConfigInvalid))
+}
+
+func Test_RequestContextMdw_WhenMissingExpAllowed(t *testing.T) {
+	cfg := &RequestContextConfig{Jwt: &JwtConfig{Keys: []*JwtKey{{Alg: JwtAlgHS256, Secret: "secret"}}, AllowMissingExp: true}}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+testToken(t, JwtAlgHS256, hs256("secret"), map[string]interface{}{"sub": "user-1"}))
+	w, rCtx := serve(t, cfg, r)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "user-1", rCtx.GetUserId())
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/exluap/kit/log"
 	"github.com/gorilla/mux"
 	"io"
+	"math"
 	"mime"
 	"mime/multipart"
 	"net/http"
@@ -20,10 +21,11 @@ import (
 
 // Error is a HTTP error object returning to clients in case of error
 type Error struct {
-	Code           string                 `json:"code,omitempty"`    // Code is error code provided by error producer
-	Message        string                 `json:"message"`           // Message is error description
-	TranslationKey string                 `json:"translationKey"`    // TranslationKey is error code translation key
-	Details        map[string]interface{} `json:"details,omitempty"` // Details is additional info provided by error producer
+	Code           string                 `json:"code,omitempty"`      // Code is error code provided by error producer
+	Message        string                 `json:"message"`             // Message is error description
+	TranslationKey string                 `json:"translationKey"`      // TranslationKey is error code translation key
+	Details        map[string]interface{} `json:"details,omitempty"`   // Details is additional info provided by error producer
+	Retryable      bool                   `json:"retryable,omitempty"` // Retryable indicates the request can be retried
 }
 
 func (e *Error) Error() string {
@@ -41,7 +43,8 @@ var EmptyOkResponse = struct {
 }
 
 type BaseController struct {
-	Logger log.CLoggerFunc
+	Logger        log.CLoggerFunc
+	ErrorRenderer ErrorRenderer // ErrorRenderer renders error responses, if not set errors are rendered as Error object
 }
 
 var MediaContentTypes = [...]string{
@@ -174,33 +177,94 @@ func (c *BaseController) GetUploadFileMultipartContent(ctx context.Context, r *h
 }
 
 func (c *BaseController) RespondJson(w http.ResponseWriter, httpStatus int, payload interface{}) {
-	response, _ := json.Marshal(payload)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(httpStatus)
-	_, _ = w.Write(response)
+	respondJson(w, httpStatus, "application/json", payload)
 }
 
+// RespondError responds error
+// message of AppError is translated to the locale of request context attached to the error (see AppErrBuilder.C)
+// use RespondErrorWithRequest if the error may have no request context
 func (c *BaseController) RespondError(w http.ResponseWriter, err error) {
+	c.respondError(w, nil, err)
+}
+
+// RespondErrorWithRequest responds error the same way as RespondError, but takes the request into account
+// message of AppError is translated to the locale taken from the request context or Accept-Language header
+func (c *BaseController) RespondErrorWithRequest(w http.ResponseWriter, r *http.Request, err error) {
+	c.respondError(w, r, err)
+}
 
+func (c *BaseController) respondError(w http.ResponseWriter, r *http.Request, err error) {
+
+	locales := requestLocales(r)
+	if len(locales) == 0 {
+		locales = errorLocales(err)
+	}
 	httpErr := &Error{}
 	httpStatus := http.StatusInternalServerError
 
-	// check if this is an app error
-	if appErr, ok := er.Is(err); ok {
-		httpErr.Code = appErr.Code()
-		httpErr.Message = appErr.Message()
-		httpErr.TranslationKey = "errors.app.code." + strings.ReplaceAll(strings.ToLower(appErr.Code()), "-", ".")
-		httpErr.Details = appErr.Fields()
+	// check if this is a multi error, then each aggregated error is put to details
+	if multiErr, ok := er.IsMulti(err); ok {
+		httpErr.Code = multiErr.Code()
+		httpErr.Message = multiErr.Message()
+		if msg, ok := er.TranslateCode(multiErr.Code(), nil, locales...); ok {
+			httpErr.Message = msg
+		}
+		httpErr.TranslationKey = er.TranslationKey(multiErr.Code())
+		items := make([]*Error, 0, multiErr.Len())
+		for _, e := range multiErr.Errors() {
+			items = append(items, toHttpError(e, locales))
+		}
+		httpErr.Details = map[string]interface{}{"errors": items}
+		httpErr.Retryable = multiErr.Retryable()
+		if httpSt := multiErr.HttpStatus(); httpSt != nil {
+			httpStatus = int(*httpSt)
+		}
+		if httpErr.Retryable {
+			setRetryAfter(w, multiErr.RetryAfter())
+		}
+	} else if appErr, ok := er.Is(err); ok {
+		// check if this is an app error
+		httpErr = toHttpError(appErr, locales)
 		if httpSt := appErr.HttpStatus(); httpSt != nil {
 			httpStatus = int(*httpSt)
 		}
+		setRetryAfter(w, appErr.RetryAfter())
 	} else {
 		httpErr.Message = err.Error()
 	}
 	if c.Logger != nil {
-		c.Logger().Cmp("api").Pr("rest").E(err).St().Err()
+		l := c.Logger().Cmp("api").Pr("rest").E(err)
+		// stack is useful only for real faults
+		if er.SeverityOf(err) == er.SeverityCritical {
+			l.St()
+		}
+		l.ErrSev()
+	}
+
+	renderer := c.ErrorRenderer
+	if renderer == nil {
+		renderer = defaultErrorRenderer
+	}
+	renderer.Render(w, r, httpStatus, httpErr)
+}
+
+// setRetryAfter sets Retry-After header in seconds if retry hint is specified
+func setRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	}
+}
+
+// toHttpError converts AppError to HTTP error object
+// message is translated to the first supported locale
+func toHttpError(appErr *er.AppError, locales []string) *Error {
+	return &Error{
+		Code:           appErr.Code(),
+		Message:        er.TranslateOrDefault(appErr, locales...),
+		TranslationKey: er.TranslationKey(appErr.Code()),
+		Details:        appErr.Fields(),
+		Retryable:      appErr.Retryable(),
 	}
-	c.RespondJson(w, httpStatus, httpErr)
 }
 
 func (c *BaseController) RespondWithStatus(w http.ResponseWriter, status int, payload interface{}) {
@@ -214,6 +278,10 @@ func (c *BaseController) RespondOK(w http.ResponseWriter, payload interface{}) {
 func (c *BaseController) DecodeRequest(r *http.Request, ctx context.Context, body interface{}) error {
 	decoder := json.NewDecoder(r.Body)
 	if err := decoder.Decode(body); err != nil {
+		// exceeded body limit is reported as is
+		if er.HasCode(err, ErrCodeHttpRequestTooLarge) {
+			return err
+		}
 		return ErrHttpDecodeRequest(err, ctx)
 	}
 	return nil
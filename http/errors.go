@@ -27,6 +27,16 @@ const (
 	ErrCodeHttpMultipartFormNameFileExpected = "HTTP-017"
 	ErrCodeHttpMultipartFilename             = "HTTP-018"
 	ErrCodeHttpCurrentClient                 = "HTTP-019"
+	ErrCodeHttpAuthTokenMissing              = "HTTP-020"
+	ErrCodeHttpAuthTokenInvalid              = "HTTP-021"
+	ErrCodeHttpAuthConfigInvalid             = "HTTP-022"
+	ErrCodeHttpClientRequest                 = "HTTP-023"
+	ErrCodeHttpClientResponse                = "HTTP-024"
+	ErrCodeHttpClientDecodeResponse          = "HTTP-025"
+	ErrCodeHttpSrvShutdown                   = "HTTP-026"
+	ErrCodeHttpWsUpgrade                     = "HTTP-027"
+	ErrCodeHttpRequestTooLarge               = "HTTP-028"
+	ErrCodeHttpTLS                           = "HTTP-029"
 )
 
 var (
@@ -86,4 +96,67 @@ var (
 	ErrHttpCurrentClient = func(ctx context.Context) error {
 		return er.WithBuilder(ErrCodeHttpCurrentClient, `cannot obtain current client`).C(ctx).HttpSt(http.StatusBadRequest).Err()
 	}
+	ErrHttpAuthTokenMissing = func(ctx context.Context) error {
+		return er.WithBuilder(ErrCodeHttpAuthTokenMissing, `access token is missing`).C(ctx).HttpSt(http.StatusUnauthorized).Err()
+	}
+	ErrHttpAuthTokenInvalid = func(cause error, ctx context.Context) error {
+		return er.WrapWithBuilder(cause, ErrCodeHttpAuthTokenInvalid, "invalid access token").C(ctx).HttpSt(http.StatusUnauthorized).Err()
+	}
+	ErrHttpAuthConfigInvalid = func(cause error) error {
+		return er.WrapWithBuilder(cause, ErrCodeHttpAuthConfigInvalid, "invalid auth configuration").Err()
+	}
+	ErrHttpClientRequest = func(cause error, ctx context.Context) error {
+		return er.WrapWithBuilder(cause, ErrCodeHttpClientRequest, "http request failed").C(ctx).Err()
+	}
+	ErrHttpClientResponse = func(ctx context.Context, status int, body string) error {
+		return er.WithBuilder(ErrCodeHttpClientResponse, "unexpected response status %d", status).F(er.FF{"status": status, "body": body}).C(ctx).HttpSt(uint32(status)).Err()
+	}
+	ErrHttpClientDecodeResponse = func(cause error, ctx context.Context) error {
+		return er.WrapWithBuilder(cause, ErrCodeHttpClientDecodeResponse, "decode response").C(ctx).Err()
+	}
+	ErrHttpSrvShutdown = func(cause error) error {
+		return er.WrapWithBuilder(cause, ErrCodeHttpSrvShutdown, "http server shutdown isn't graceful").Err()
+	}
+	ErrHttpWsUpgrade = func(cause error, ctx context.Context) error {
+		return er.WrapWithBuilder(cause, ErrCodeHttpWsUpgrade, "websocket upgrade").C(ctx).Err()
+	}
+	ErrHttpRequestTooLarge = func(ctx context.Context, limit int64) error {
+		return er.WithBuilder(ErrCodeHttpRequestTooLarge, "request body is too large").F(er.FF{"limit": limit}).C(ctx).HttpSt(http.StatusRequestEntityTooLarge).Err()
+	}
+	ErrHttpTLS = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeHttpTLS, "tls configuration").Err() }
 )
+
+func init() {
+	er.Register(
+		er.CatalogEntry{Code: ErrCodeHttpTest, Message: "test"},
+		er.CatalogEntry{Code: ErrCodeHttpSrvListen, Message: "http server listen failed"},
+		er.CatalogEntry{Code: ErrCodeDecodeRequest, Message: "invalid request", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpUrlVar, Message: "invalid or empty URL parameter", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpCurrentUser, Message: "cannot obtain current user", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpUrlVarEmpty, Message: "URL parameter is empty", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpUrlFormVarEmpty, Message: "URL form value is empty", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpUrlFormVarNotInt, Message: "form value must be of int type", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpUrlFormVarNotTime, Message: "form value must be of time type in RFC-3339 format", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpMultipartParseForm, Message: "parse multipart form", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpMultipartEmptyContent, Message: "content is empty", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpMultipartNotMultipart, Message: "content isn't multipart", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpMultipartParseMediaType, Message: "parse media type", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpMultipartWrongMediaType, Message: "wrong media type", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpMultipartMissingBoundary, Message: "missing boundary", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpMultipartEofReached, Message: "no parts found", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpMultipartNext, Message: "reading part", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpMultipartFormNameFileExpected, Message: `correct part must have name="file" param`, HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpMultipartFilename, Message: "filename is empty", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpCurrentClient, Message: "cannot obtain current client", HttpStatus: er.St(http.StatusBadRequest)},
+		er.CatalogEntry{Code: ErrCodeHttpAuthTokenMissing, Message: "access token is missing", HttpStatus: er.St(http.StatusUnauthorized)},
+		er.CatalogEntry{Code: ErrCodeHttpAuthTokenInvalid, Message: "invalid access token", HttpStatus: er.St(http.StatusUnauthorized)},
+		er.CatalogEntry{Code: ErrCodeHttpAuthConfigInvalid, Message: "invalid auth configuration"},
+		er.CatalogEntry{Code: ErrCodeHttpClientRequest, Message: "http request failed"},
+		er.CatalogEntry{Code: ErrCodeHttpClientResponse, Message: "unexpected response status"},
+		er.CatalogEntry{Code: ErrCodeHttpClientDecodeResponse, Message: "decode response"},
+		er.CatalogEntry{Code: ErrCodeHttpSrvShutdown, Message: "http server shutdown isn't graceful"},
+		er.CatalogEntry{Code: ErrCodeHttpWsUpgrade, Message: "websocket upgrade"},
+		er.CatalogEntry{Code: ErrCodeHttpRequestTooLarge, Message: "request body is too large", HttpStatus: er.St(http.StatusRequestEntityTooLarge)},
+		er.CatalogEntry{Code: ErrCodeHttpTLS, Message: "tls configuration"},
+	)
+}
@@ -0,0 +1,237 @@
+package http
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+const (
+	JwtAlgHS256 = "HS256"
+	JwtAlgRS256 = "RS256"
+)
+
+// JwtKey is a key used to verify JWT signature
+type JwtKey struct {
+	Kid           string `config:"kid"`             // Kid - key id, if specified, it must match "kid" header of the token
+	Alg           string `config:"alg"`             // Alg - HS256 or RS256
+	Secret        string `config:"secret"`          // Secret - HMAC secret for HS256
+	PublicKey     string `config:"public-key"`      // PublicKey - PEM encoded RSA public key for RS256
+	PublicKeyFile string `config:"public-key-file"` // PublicKeyFile - path to PEM encoded RSA public key for RS256
+}
+
+// JwtConfig specifies JWT validation
+type JwtConfig struct {
+	Keys     []*JwtKey     `config:"keys"`     // Keys - local key set
+	Issuer   string        `config:"issuer"`   // Issuer - if specified, "iss" claim must match
+	Audience string        `config:"audience"` // Audience - if specified, "aud" claim must contain it
+	Leeway   time.Duration `config:"leeway"`   // Leeway - allowed clock skew when checking exp/nbf
+	// AllowMissingExp - if true, tokens without "exp" claim are accepted, otherwise they are rejected as never expiring
+	AllowMissingExp bool `config:"allow-missing-exp"`
+}
+
+type jwtKey struct {
+	kid  string
+	alg  string
+	hmac []byte
+	rsa  *rsa.PublicKey
+}
+
+// jwtVerifier verifies JWT against local key set
+type jwtVerifier struct {
+	cfg  *JwtConfig
+	keys []*jwtKey
+	now  func() time.Time
+}
+
+func newJwtVerifier(cfg *JwtConfig) (*jwtVerifier, error) {
+	v := &jwtVerifier{cfg: cfg, now: time.Now}
+	for _, k := range cfg.Keys {
+		key := &jwtKey{kid: k.Kid, alg: k.Alg}
+		switch k.Alg {
+		case JwtAlgHS256:
+			if k.Secret == "" {
+				return nil, fmt.Errorf("key %s: secret is empty", k.Kid)
+			}
+			key.hmac = []byte(k.Secret)
+		case JwtAlgRS256:
+			pemData := []byte(k.PublicKey)
+			if k.PublicKeyFile != "" {
+				data, err := ioutil.ReadFile(k.PublicKeyFile)
+				if err != nil {
+					return nil, err
+				}
+				pemData = data
+			}
+			pub, err := parseRsaPublicKey(pemData)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %v", k.Kid, err)
+			}
+			key.rsa = pub
+		default:
+			return nil, fmt.Errorf("key %s: algorithm %s isn't supported", k.Kid, k.Alg)
+		}
+		v.keys = append(v.keys, key)
+	}
+	if len(v.keys) == 0 {
+		return nil, errors.New("no keys specified")
+	}
+	return v, nil
+}
+
+func parseRsaPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM")
+	}
+	if pub, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		if rsaPub, ok := pub.(*rsa.PublicKey); ok {
+			return rsaPub, nil
+		}
+		return nil, errors.New("not RSA public key")
+	}
+	if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+		if rsaPub, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+			return rsaPub, nil
+		}
+		return nil, errors.New("not RSA public key")
+	}
+	return x509.ParsePKCS1PublicKey(block.Bytes)
+}
+
+// verify checks token signature and standard claims and returns claims
+func (v *jwtVerifier) verify(token string) (map[string]interface{}, error) {
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJwtPart(parts[0], &header); err != nil {
+		return nil, errors.New("malformed header")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, k := range v.keys {
+		if k.alg != header.Alg || (header.Kid != "" && k.kid != "" && k.kid != header.Kid) {
+			continue
+		}
+		if k.verify(signed, sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("invalid signature")
+	}
+
+	claims := make(map[string]interface{})
+	if err := decodeJwtPart(parts[1], &claims); err != nil {
+		return nil, errors.New("malformed claims")
+	}
+
+	now := v.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok && !v.cfg.AllowMissingExp {
+		return nil, errors.New("token has no expiration")
+	}
+	if ok && now.After(time.Unix(int64(exp), 0).Add(v.cfg.Leeway)) {
+		return nil, errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.cfg.Leeway).Before(time.Unix(int64(nbf), 0)) {
+		return nil, errors.New("token not valid yet")
+	}
+	if v.cfg.Issuer != "" && claims["iss"] != v.cfg.Issuer {
+		return nil, errors.New("invalid issuer")
+	}
+	if v.cfg.Audience != "" && !containsAudience(claims["aud"], v.cfg.Audience) {
+		return nil, errors.New("invalid audience")
+	}
+
+	return claims, nil
+}
+
+func (k *jwtKey) verify(signed, sig []byte) bool {
+	switch k.alg {
+	case JwtAlgHS256:
+		mac := hmac.New(sha256.New, k.hmac)
+		mac.Write(signed)
+		return hmac.Equal(sig, mac.Sum(nil))
+	case JwtAlgRS256:
+		h := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(k.rsa, crypto.SHA256, h[:], sig) == nil
+	}
+	return false
+}
+
+func decodeJwtPart(part string, target interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+func containsAudience(aud interface{}, expected string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == expected
+	case []interface{}:
+		for _, item := range a {
+			if item == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// claimValue takes claim by path, nested claims are separated by dot (e.g. realm_access.roles)
+func claimValue(claims map[string]interface{}, path string) interface{} {
+	var cur interface{} = claims
+	for _, p := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[p]
+	}
+	return cur
+}
+
+// claimStrings converts claim to list of strings, it can be either an array or space separated string
+func claimStrings(v interface{}) []string {
+	switch c := v.(type) {
+	case string:
+		return strings.Fields(c)
+	case []interface{}:
+		res := make([]string, 0, len(c))
+		for _, item := range c {
+			if s, ok := item.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	return nil
+}
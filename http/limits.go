@@ -0,0 +1,42 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// bodyLimitMiddleware rejects requests with body exceeding MaxBodyBytes
+// requests with known content length are rejected immediately, otherwise reading of body fails when the limit is exceeded
+func (s *Server) bodyLimitMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limit := s.cfg.MaxBodyBytes
+		if r.ContentLength > limit {
+			c := &BaseController{Logger: s.logger, ErrorRenderer: s.ErrorRenderer}
+			c.RespondErrorWithRequest(w, r, ErrHttpRequestTooLarge(r.Context(), limit))
+			return
+		}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, limit), ctx: r.Context(), limit: limit}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitedBody converts error of exceeded limit to AppError
+type limitedBody struct {
+	io.ReadCloser
+	ctx   context.Context
+	limit int64
+	read  int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	// MaxBytesReader reports exceeded limit with a plain error, so it's recognized by the number of bytes read
+	if err != nil && err != io.EOF && b.read >= b.limit {
+		return n, ErrHttpRequestTooLarge(b.ctx, b.limit)
+	}
+	return n, err
+}
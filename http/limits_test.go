@@ -0,0 +1,166 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"github.com/exluap/kit/er"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Config_Defaults(t *testing.T) {
+	s := NewHttpServer(&Config{}, testLogger())
+	assert.Equal(t, ReadTimeout, s.Srv.ReadTimeout)
+	assert.Equal(t, WriteTimeout, s.Srv.WriteTimeout)
+	assert.Equal(t, ReadBufferSize, s.WsUpgrader.ReadBufferSize)
+	assert.Nil(t, s.Srv.TLSNextProto)
+
+	s = NewHttpServer(&Config{ReadTimeout: time.Second, WriteTimeout: time.Minute, IdleTimeout: time.Hour, MaxHeaderBytes: 100, WsReadBufferSize: 10, DisableHTTP2: true}, testLogger())
+	assert.Equal(t, time.Second, s.Srv.ReadTimeout)
+	assert.Equal(t, time.Minute, s.Srv.WriteTimeout)
+	assert.Equal(t, time.Hour, s.Srv.IdleTimeout)
+	assert.Equal(t, 100, s.Srv.MaxHeaderBytes)
+	assert.Equal(t, 10, s.WsUpgrader.ReadBufferSize)
+	assert.NotNil(t, s.Srv.TLSNextProto)
+	assert.Empty(t, s.Srv.TLSNextProto)
+}
+
+func Test_BodyLimit(t *testing.T) {
+	s := NewHttpServer(&Config{MaxBodyBytes: 10}, testLogger())
+	c := &BaseController{ErrorRenderer: s.ErrorRenderer}
+	s.RootRouter.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := c.DecodeRequest(r, r.Context(), &body); err != nil {
+			c.RespondErrorWithRequest(w, r, err)
+			return
+		}
+		c.RespondOK(w, EmptyOkResponse)
+	}).Methods(http.MethodPost)
+	ts := httptest.NewServer(s.Srv.Handler)
+	defer ts.Close()
+
+	post := func(body io.Reader) (int, string) {
+		rs, err := http.Post(ts.URL, "application/json", body)
+		assert.Nil(t, err)
+		defer rs.Body.Close()
+		b, _ := ioutil.ReadAll(rs.Body)
+		return rs.StatusCode, string(b)
+	}
+
+	st, _ := post(strings.NewReader(`{"a":1}`))
+	assert.Equal(t, http.StatusOK, st)
+
+	// content length is known
+	st, body := post(strings.NewReader(`{"a":"long value"}`))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, st)
+	assert.Contains(t, body, ErrCodeHttpRequestTooLarge)
+
+	// chunked body
+	st, body = post(io.MultiReader(strings.NewReader(`{"a":`), strings.NewReader(`"long value"}`)))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, st)
+	assert.Contains(t, body, ErrCodeHttpRequestTooLarge)
+
+	// limit is applied to requests not matching routes and with request context middleware
+	assert.Nil(t, s.UseRequestContext(&RequestContextConfig{}))
+	ts2 := httptest.NewServer(s.Srv.Handler)
+	defer ts2.Close()
+	rs, err := http.Post(ts2.URL+"/unknown", "application/json", strings.NewReader(`{"a":"long value"}`))
+	assert.Nil(t, err)
+	_ = rs.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rs.StatusCode)
+}
+
+// writeTestCert generates self-signed certificate valid for client and server auth
+func writeTestCert(t *testing.T, dir, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	assert.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func Test_TLS_ClientAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kit-http-tls")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	srvCert, srvKey := writeTestCert(t, dir, "localhost")
+	clCert, clKey := writeTestCert(t, dir, "client")
+
+	s, addr := startTestServer(t, &Config{TLS: &TLSConfig{CertFile: srvCert, KeyFile: srvKey, ClientCAFile: clCert, ClientAuth: true}}, func(s *Server) {
+		s.RootRouter.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			(&BaseController{}).RespondOK(w, EmptyOkResponse)
+		})
+	})
+	defer s.Close()
+
+	pem, _ := ioutil.ReadFile(srvCert)
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(pem)
+	url := fmt.Sprintf("https://localhost:%s/", strings.Split(addr, ":")[1])
+
+	// without client certificate
+	cl := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	_, err = cl.Get(url)
+	assert.Error(t, err)
+
+	cert, err := tls.LoadX509KeyPair(clCert, clKey)
+	assert.Nil(t, err)
+	cl = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{cert}}, ForceAttemptHTTP2: true}}
+	rs, err := cl.Get(url)
+	assert.Nil(t, err)
+	_ = rs.Body.Close()
+	assert.Equal(t, http.StatusOK, rs.StatusCode)
+	assert.Equal(t, 2, rs.ProtoMajor)
+}
+
+func Test_TLS_WhenInvalidConfig(t *testing.T) {
+	_, err := newServerTLSConfig(&TLSConfig{})
+	assert.Error(t, err)
+	_, err = newServerTLSConfig(&TLSConfig{CertFile: "a", KeyFile: "b", ClientAuth: true})
+	assert.Error(t, err)
+	_, err = newServerTLSConfig(&TLSConfig{CertFile: "a", KeyFile: "b"})
+	assert.Error(t, err)
+
+	// found on creation
+	_, err = NewServer(&Config{TLS: &TLSConfig{CertFile: "a", KeyFile: "b"}}, testLogger())
+	assert.True(t, er.HasCode(err, ErrCodeHttpTLS))
+	s := NewHttpServer(&Config{TLS: &TLSConfig{CertFile: "a", KeyFile: "b"}}, testLogger())
+	s.Listen()
+	<-s.Done()
+	assert.False(t, s.Ready())
+	assert.True(t, er.HasCode(s.Err(), ErrCodeHttpTLS))
+
+	// repeated listening is ignored
+	assert.NotPanics(t, s.Listen)
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	kitContext "github.com/exluap/kit/context"
+	"github.com/exluap/kit/er"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// requestLocales returns preferred locales of the caller
+// locales from the request context take precedence over Accept-Language header
+func requestLocales(r *http.Request) []string {
+	if r == nil {
+		return nil
+	}
+	if rCtx, ok := kitContext.Request(r.Context()); ok && len(rCtx.GetLang()) > 0 {
+		return rCtx.GetLang()
+	}
+	return ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
+// errorLocales returns locales of request context attached to AppError (see AppErrBuilder.C)
+// for multi error, the first aggregated error having request context is taken
+func errorLocales(err error) []string {
+	var errs []*er.AppError
+	if multiErr, ok := er.IsMulti(err); ok {
+		errs = multiErr.Errors()
+	} else if appErr, ok := er.Is(err); ok {
+		errs = []*er.AppError{appErr}
+	}
+	for _, e := range errs {
+		ctx, ok := e.Fields()["ctx"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch lang := ctx["_ctx.lang"].(type) {
+		case []string:
+			if len(lang) > 0 {
+				return lang
+			}
+		case []interface{}:
+			// context passed through serialization
+			var res []string
+			for _, l := range lang {
+				if s, ok := l.(string); ok {
+					res = append(res, s)
+				}
+			}
+			if len(res) > 0 {
+				return res
+			}
+		}
+	}
+	return nil
+}
+
+// ParseAcceptLanguage parses Accept-Language header and returns locales ordered by quality
+// locales are converted to the form used by translators (en-US -> en_US), base language is added as a fallback
+// example: "ru-RU,ru;q=0.9,en;q=0.8" -> [ru_RU ru en]
+func ParseAcceptLanguage(header string) []string {
+
+	type lang struct {
+		tag string
+		q   float64
+	}
+
+	var langs []lang
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		l := lang{tag: part, q: 1}
+		if i := strings.Index(part, ";"); i >= 0 {
+			l.tag = strings.TrimSpace(part[:i])
+			for _, p := range strings.Split(part[i+1:], ";") {
+				p = strings.TrimSpace(p)
+				if strings.HasPrefix(p, "q=") {
+					if q, err := strconv.ParseFloat(p[2:], 64); err == nil {
+						l.q = q
+					}
+				}
+			}
+		}
+		if l.tag == "" || l.tag == "*" || l.q <= 0 {
+			continue
+		}
+		langs = append(langs, l)
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+
+	var res []string
+	added := make(map[string]struct{})
+	add := func(tag string) {
+		if _, ok := added[tag]; !ok {
+			added[tag] = struct{}{}
+			res = append(res, tag)
+		}
+	}
+	for _, l := range langs {
+		tag := strings.ReplaceAll(l.tag, "-", "_")
+		add(tag)
+		if i := strings.Index(tag, "_"); i > 0 {
+			add(tag[:i])
+		}
+	}
+	return res
+}
@@ -0,0 +1,36 @@
+package http
+
+import (
+	"context"
+	kitContext "github.com/exluap/kit/context"
+	"github.com/exluap/kit/er"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func Test_ParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []string{"ru_RU", "ru", "en"}, ParseAcceptLanguage("en;q=0.8, ru-RU,ru;q=0.9"))
+	assert.Equal(t, []string{"de"}, ParseAcceptLanguage("de, *;q=0.5, fr;q=0"))
+	assert.Empty(t, ParseAcceptLanguage(""))
+}
+
+func Test_RequestLocales_WhenContext(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "en")
+	assert.Equal(t, []string{"en"}, requestLocales(r))
+
+	ctx := kitContext.NewRequestCtx().Rest().WithLang("ru").ToContext(context.Background())
+	assert.Equal(t, []string{"ru"}, requestLocales(r.WithContext(ctx)))
+}
+
+func Test_ErrorLocales(t *testing.T) {
+	ctx := kitContext.NewRequestCtx().Rest().WithLang("ru").ToContext(context.Background())
+	assert.Equal(t, []string{"ru"}, errorLocales(er.WithBuilder("TST-LC-001", "error").C(ctx).Err()))
+	assert.Equal(t, []string{"ru"}, errorLocales(er.NewMulti("TST-LC-002", "errors").Add(er.New("TST-LC-001", "error"), er.WithBuilder("TST-LC-001", "error").C(ctx).Err())))
+	assert.Empty(t, errorLocales(er.New("TST-LC-001", "error")))
+
+	// context passed through serialization
+	assert.Equal(t, []string{"ru"}, errorLocales(er.WithBuilder("TST-LC-001", "error").F(er.FF{"ctx": map[string]interface{}{"_ctx.lang": []interface{}{"ru"}}}).Err()))
+}
@@ -1,37 +1,270 @@
 package http
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
+	"fmt"
 	"github.com/exluap/kit/log"
+	"io"
 	"io/ioutil"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
+	"strings"
 )
 
+const (
+	TraceMaxBodyBytes = 4096
+	redacted          = "***"
+)
+
+// headers redacted by default
+var defaultRedactHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", ContextHeader}
+
+// TraceConfig is configuration of request/response tracing enabled by Trace flag
+type TraceConfig struct {
+	// RedactHeaders - headers which values are masked in addition to Authorization, Cookie, Set-Cookie and context header
+	RedactHeaders []string `config:"redact-headers"`
+	// RedactBodyPaths - dotted paths of JSON body fields which values are masked (e.g. password, user.token), arrays are traversed
+	RedactBodyPaths []string `config:"redact-body-paths"`
+	// MaxBodyBytes - max size of logged body, longer bodies are truncated, 4KB by default
+	MaxBodyBytes int `config:"max-body-bytes"`
+	// TextContentTypes - content types which bodies are logged in addition to text, JSON, XML and form ones
+	TextContentTypes []string `config:"text-content-types"`
+}
+
+// tracer logs requests and responses according to configuration
+type tracer struct {
+	redactHeaders map[string]struct{}
+	redactPaths   [][]string
+	maxBodyBytes  int
+	textTypes     map[string]struct{}
+}
+
+func newTracer(cfg *TraceConfig) *tracer {
+	if cfg == nil {
+		cfg = &TraceConfig{}
+	}
+	t := &tracer{
+		redactHeaders: make(map[string]struct{}),
+		maxBodyBytes:  cfg.MaxBodyBytes,
+		textTypes:     make(map[string]struct{}),
+	}
+	if t.maxBodyBytes <= 0 {
+		t.maxBodyBytes = TraceMaxBodyBytes
+	}
+	for _, h := range append(defaultRedactHeaders, cfg.RedactHeaders...) {
+		t.redactHeaders[http.CanonicalHeaderKey(h)] = struct{}{}
+	}
+	for _, p := range cfg.RedactBodyPaths {
+		t.redactPaths = append(t.redactPaths, strings.Split(p, "."))
+	}
+	for _, ct := range cfg.TextContentTypes {
+		t.textTypes[strings.ToLower(ct)] = struct{}{}
+	}
+	return t
+}
+
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		body, err := ioutil.ReadAll(r.Body)
-		if err == nil {
-			r.Body = ioutil.NopCloser(bytes.NewReader(body))
-			s.logger().C(r.Context()).F(log.FF{"method": r.Method, "URL": r.URL.Path, "headers": r.Header, "body": string(body)}).Trc("request")
+
+		rqBody := "[skipped]"
+		if s.tracer.loggable(r.Header.Get("Content-Type")) {
+			// only head of the body is read, the rest is left for the handler
+			var head []byte
+			head, r.Body = s.tracer.peekBody(r.Body)
+			rqBody = s.tracer.body(head, r.Header.Get("Content-Type"))
 		}
-		loggableRsp := &loggableResponseWriter{ResponseWriter: w}
+		s.logger().C(r.Context()).F(log.FF{"method": r.Method, "URL": r.URL.Path, "headers": s.tracer.headers(r.Header), "body": rqBody}).Trc("request")
+
+		loggableRsp := &loggableResponseWriter{ResponseWriter: w, tracer: s.tracer}
 
 		next.ServeHTTP(loggableRsp, r)
 
-		s.logger().C(r.Context()).F(log.FF{"status": loggableRsp.StatusCode, "headers": loggableRsp.Header(), "body": string(loggableRsp.Body)}).Trc("response")
+		rsBody := "[skipped]"
+		if !loggableRsp.skip {
+			rsBody = s.tracer.body(loggableRsp.Body, w.Header().Get("Content-Type"))
+		}
+		s.logger().C(r.Context()).F(log.FF{"status": loggableRsp.status(), "headers": s.tracer.headers(w.Header()), "body": rsBody, "size": loggableRsp.Size}).Trc("response")
 	})
 }
 
+// loggable checks if body of the content type is logged
+// bodies without content type are logged, as they are mostly empty or text
+func (t *tracer) loggable(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if _, ok := t.textTypes[mt]; ok {
+		return true
+	}
+	switch {
+	case strings.HasPrefix(mt, "text/"),
+		mt == "application/json", strings.HasSuffix(mt, "+json"),
+		mt == "application/xml", strings.HasSuffix(mt, "+xml"),
+		mt == "application/x-www-form-urlencoded":
+		return true
+	}
+	return false
+}
+
+// peekBody reads up to max+1 bytes of the body and returns a body which yields the whole content
+func (t *tracer) peekBody(body io.ReadCloser) ([]byte, io.ReadCloser) {
+	if body == nil || body == http.NoBody {
+		return nil, body
+	}
+	head, _ := ioutil.ReadAll(io.LimitReader(body, int64(t.maxBodyBytes+1)))
+	return head, &peekedBody{Reader: io.MultiReader(bytes.NewReader(head), body), Closer: body}
+}
+
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// body prepares body for logging: JSON fields and form values are redacted, long bodies are truncated
+// truncated JSON and forms cannot be redacted, so they aren't logged if redaction is configured
+// body without content type is considered JSON if it looks like JSON
+func (t *tracer) body(b []byte, contentType string) string {
+	truncated := len(b) > t.maxBodyBytes
+	if truncated {
+		b = b[:t.maxBodyBytes]
+	}
+	if len(t.redactPaths) > 0 {
+		jsonBody, formBody := isJson(contentType) || contentType == "" && looksLikeJson(b), isForm(contentType)
+		if truncated && (jsonBody || formBody) {
+			return fmt.Sprintf("[truncated, more than %d bytes]", t.maxBodyBytes)
+		}
+		switch {
+		case jsonBody:
+			b = t.redactJson(b)
+		case formBody:
+			b = t.redactForm(b)
+		}
+	}
+	if truncated {
+		return string(b) + fmt.Sprintf("...[truncated, more than %d bytes]", t.maxBodyBytes)
+	}
+	return string(b)
+}
+
+// redactJson masks values of JSON fields by paths, invalid JSON is returned as is
+func (t *tracer) redactJson(b []byte) []byte {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return b
+	}
+	for _, p := range t.redactPaths {
+		redactPath(v, p)
+	}
+	res, _ := json.Marshal(v)
+	return res
+}
+
+// redactForm masks values of form fields which names match paths (e.g. password, user.token)
+// order and encoding of other fields are kept
+func (t *tracer) redactForm(b []byte) []byte {
+	pairs := strings.Split(string(b), "&")
+	for i, pair := range pairs {
+		k := strings.SplitN(pair, "=", 2)[0]
+		if name, err := url.QueryUnescape(k); err == nil && t.redactedField(name) {
+			pairs[i] = k + "=" + redacted
+		}
+	}
+	return []byte(strings.Join(pairs, "&"))
+}
+
+func (t *tracer) redactedField(name string) bool {
+	for _, p := range t.redactPaths {
+		if strings.Join(p, ".") == name {
+			return true
+		}
+	}
+	return false
+}
+
+// headers returns copy of headers with redacted values
+func (t *tracer) headers(h http.Header) http.Header {
+	res := make(http.Header, len(h))
+	for k, v := range h {
+		if _, ok := t.redactHeaders[http.CanonicalHeaderKey(k)]; ok {
+			res[k] = []string{redacted}
+			continue
+		}
+		res[k] = v
+	}
+	return res
+}
+
+func isJson(contentType string) bool {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+func isForm(contentType string) bool {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	return mt == "application/x-www-form-urlencoded"
+}
+
+// looksLikeJson checks if body is a JSON object or array
+func looksLikeJson(b []byte) bool {
+	b = bytes.TrimSpace(b)
+	return len(b) > 0 && (b[0] == '{' || b[0] == '[')
+}
+
+// redactPath masks value by path, arrays are traversed
+func redactPath(v interface{}, path []string) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		f, ok := val[path[0]]
+		if !ok {
+			return
+		}
+		if len(path) == 1 {
+			val[path[0]] = redacted
+			return
+		}
+		redactPath(f, path[1:])
+	case []interface{}:
+		for _, item := range val {
+			redactPath(item, path)
+		}
+	}
+}
+
+// loggableResponseWriter captures head of the response body for logging
+// it passes through Flusher, Hijacker and Pusher, so streaming and websockets keep working
 type loggableResponseWriter struct {
 	http.ResponseWriter
+	tracer      *tracer
 	Body        []byte
 	StatusCode  int
+	Size        int
 	wroteHeader bool
+	skip        bool
 }
 
 func (rw *loggableResponseWriter) Write(data []byte) (int, error) {
-	rw.Body = append(rw.Body, data...)
-	return rw.ResponseWriter.Write(data)
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	// capture a byte over the limit, so truncation is detected
+	if !rw.skip && len(rw.Body) <= rw.tracer.maxBodyBytes {
+		n := rw.tracer.maxBodyBytes + 1 - len(rw.Body)
+		if n > len(data) {
+			n = len(data)
+		}
+		rw.Body = append(rw.Body, data[:n]...)
+	}
+	n, err := rw.ResponseWriter.Write(data)
+	rw.Size += n
+	return n, err
 }
 
 func (rw *loggableResponseWriter) WriteHeader(code int) {
@@ -40,6 +273,34 @@ func (rw *loggableResponseWriter) WriteHeader(code int) {
 	}
 
 	rw.StatusCode = code
+	rw.skip = !rw.tracer.loggable(rw.Header().Get("Content-Type"))
 	rw.ResponseWriter.WriteHeader(code)
 	rw.wroteHeader = true
 }
+
+func (rw *loggableResponseWriter) status() int {
+	if rw.StatusCode == 0 {
+		return http.StatusOK
+	}
+	return rw.StatusCode
+}
+
+func (rw *loggableResponseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (rw *loggableResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := rw.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+func (rw *loggableResponseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := rw.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
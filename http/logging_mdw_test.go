@@ -0,0 +1,98 @@
+package http
+
+import (
+	"bytes"
+	"github.com/exluap/kit/log"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Tracer_Body(t *testing.T) {
+	tr := newTracer(&TraceConfig{RedactBodyPaths: []string{"password", "user.token"}, MaxBodyBytes: 100})
+
+	body := tr.body([]byte(`{"login":"u","password":"secret","user":[{"token":"t1"},{"token":"t2","name":"n"}]}`), "application/json; charset=utf-8")
+	assert.NotContains(t, body, "secret")
+	assert.NotContains(t, body, "t1")
+	assert.NotContains(t, body, "t2")
+	assert.Contains(t, body, `"login":"u"`)
+	assert.Contains(t, body, `"name":"n"`)
+
+	// truncated JSON cannot be redacted
+	body = tr.body([]byte(`{"password":"`+strings.Repeat("a", 200)+`"}`), "application/json")
+	assert.NotContains(t, body, "aaa")
+	assert.Contains(t, body, "truncated")
+
+	// JSON without content type
+	body = tr.body([]byte(` {"password":"secret"}`), "")
+	assert.NotContains(t, body, "secret")
+
+	// form values
+	body = tr.body([]byte(`login=u&password=secret&user.token=t1`), "application/x-www-form-urlencoded")
+	assert.Equal(t, "login=u&password=***&user.token=***", body)
+	body = tr.body([]byte(`password=`+strings.Repeat("a", 200)), "application/x-www-form-urlencoded")
+	assert.NotContains(t, body, "aaa")
+
+	body = tr.body([]byte(strings.Repeat("a", 200)), "text/plain")
+	assert.True(t, strings.HasPrefix(body, strings.Repeat("a", 100)+"...[truncated"))
+
+	assert.True(t, tr.loggable(""))
+	assert.True(t, tr.loggable("application/problem+json"))
+	assert.False(t, tr.loggable("image/png"))
+	assert.False(t, tr.loggable("application/octet-stream"))
+	assert.True(t, newTracer(&TraceConfig{TextContentTypes: []string{"application/octet-stream"}}).loggable("application/octet-stream"))
+}
+
+func Test_LoggingMdw(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger := log.Init(&log.Config{Level: log.TraceLevel, Format: log.FormatterJson})
+	logger.Logrus.SetOutput(out)
+
+	s := NewHttpServer(&Config{Trace: true, Tracing: &TraceConfig{RedactHeaders: []string{"X-Api-Key"}, RedactBodyPaths: []string{"password"}, MaxBodyBytes: 64}}, func() log.CLogger { return log.L(logger) })
+	s.RootRouter.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		// handler gets the whole body
+		b, _ := ioutil.ReadAll(r.Body)
+		assert.Contains(t, string(b), "secret")
+		w.Header().Set("Set-Cookie", "session=cookie-value")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"password":"secret","ok":true}`))
+	})
+	s.RootRouter.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		f, ok := w.(http.Flusher)
+		assert.True(t, ok)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write([]byte("binary"))
+		f.Flush()
+	})
+	ts := httptest.NewServer(s.Srv.Handler)
+	defer ts.Close()
+
+	rq, _ := http.NewRequest(http.MethodPost, ts.URL+"/login", strings.NewReader(`{"login":"u","password":"secret"}`))
+	rq.Header.Set("Content-Type", "application/json")
+	rq.Header.Set("Authorization", "Bearer token-value")
+	rq.Header.Set("X-Api-Key", "key-value")
+	rs, err := http.DefaultClient.Do(rq)
+	assert.Nil(t, err)
+	b, _ := ioutil.ReadAll(rs.Body)
+	_ = rs.Body.Close()
+	assert.Contains(t, string(b), "secret")
+
+	logs := out.String()
+	assert.Contains(t, logs, `\"login\":\"u\"`)
+	assert.Contains(t, logs, `\"ok\":true`)
+	for _, secret := range []string{"secret", "token-value", "key-value", "cookie-value"} {
+		assert.NotContains(t, logs, secret)
+	}
+
+	out.Reset()
+	rs, err = http.Get(ts.URL + "/stream")
+	assert.Nil(t, err)
+	b, _ = ioutil.ReadAll(rs.Body)
+	_ = rs.Body.Close()
+	assert.Equal(t, "binary", string(b))
+	assert.Contains(t, out.String(), "[skipped]")
+	assert.NotContains(t, out.String(), `"body":"binary"`)
+}
@@ -0,0 +1,131 @@
+package http
+
+import (
+	"encoding/json"
+	kitContext "github.com/exluap/kit/context"
+	"github.com/exluap/kit/er"
+	"net/http"
+	"strings"
+)
+
+const (
+	ErrorFormatKit     = "kit"     // ErrorFormatKit renders errors as Error object (default)
+	ErrorFormatProblem = "problem" // ErrorFormatProblem renders errors as RFC 7807 problem details
+
+	problemContentType = "application/problem+json"
+)
+
+// ErrorRenderer renders error response
+type ErrorRenderer interface {
+	// Render writes error response
+	// request might be nil if it isn't available
+	Render(w http.ResponseWriter, r *http.Request, httpStatus int, httpErr *Error)
+}
+
+var defaultErrorRenderer = &kitErrorRenderer{}
+
+// NewErrorRenderer creates error renderer according to the configuration
+func NewErrorRenderer(cfg *Config) ErrorRenderer {
+	if cfg != nil && cfg.ErrorFormat == ErrorFormatProblem {
+		return NewProblemRenderer(cfg.ProblemTypeBaseUrl)
+	}
+	return defaultErrorRenderer
+}
+
+// kitErrorRenderer renders errors as Error object
+type kitErrorRenderer struct{}
+
+func (k *kitErrorRenderer) Render(w http.ResponseWriter, r *http.Request, httpStatus int, httpErr *Error) {
+	respondJson(w, httpStatus, "application/json", httpErr)
+}
+
+// ProblemRenderer renders errors as RFC 7807 problem details (application/problem+json)
+type ProblemRenderer struct {
+	// TypeBaseUrl is a base URL of problem type, type is built as TypeBaseUrl + "/" + error code
+	// if empty, "about:blank" is used as type
+	TypeBaseUrl string
+}
+
+// NewProblemRenderer creates a new RFC 7807 renderer
+func NewProblemRenderer(typeBaseUrl string) *ProblemRenderer {
+	return &ProblemRenderer{TypeBaseUrl: strings.TrimSuffix(typeBaseUrl, "/")}
+}
+
+// problem members defined by RFC 7807, extensions mustn't override them
+var problemMembers = map[string]struct{}{"type": {}, "title": {}, "status": {}, "detail": {}, "instance": {}}
+
+// privateDetails are error details never exposed as extensions
+// "ctx" carries the request context (user, roles, session), only request id is taken from it
+var privateDetails = map[string]struct{}{"ctx": {}}
+
+func (p *ProblemRenderer) Render(w http.ResponseWriter, r *http.Request, httpStatus int, httpErr *Error) {
+
+	problem := make(map[string]interface{}, len(httpErr.Details)+7)
+
+	// extensions come from error details
+	for k, v := range httpErr.Details {
+		if _, ok := problemMembers[k]; ok {
+			continue
+		}
+		if _, ok := privateDetails[k]; ok {
+			continue
+		}
+		problem[k] = v
+	}
+
+	problem["type"] = p.typeUri(httpErr.Code)
+	problem["title"] = p.title(httpStatus, httpErr.Code)
+	problem["status"] = httpStatus
+	if httpErr.Message != "" {
+		problem["detail"] = httpErr.Message
+	}
+	if rid := requestId(r, httpErr); rid != "" {
+		problem["instance"] = rid
+	}
+	if httpErr.Code != "" {
+		problem["code"] = httpErr.Code
+		problem["translationKey"] = httpErr.TranslationKey
+	}
+	if httpErr.Retryable {
+		problem["retryable"] = true
+	}
+
+	respondJson(w, httpStatus, problemContentType, problem)
+}
+
+func (p *ProblemRenderer) typeUri(code string) string {
+	if code == "" || p.TypeBaseUrl == "" {
+		return "about:blank"
+	}
+	return p.TypeBaseUrl + "/" + strings.ToLower(code)
+}
+
+// title is a short summary of the problem type, so it's taken from the error catalog if registered
+func (p *ProblemRenderer) title(httpStatus int, code string) string {
+	if e, ok := er.Lookup(code); ok && e.Message != "" {
+		return e.Message
+	}
+	return http.StatusText(httpStatus)
+}
+
+// requestId takes request id from the request context, if not available from the context attached to error
+func requestId(r *http.Request, httpErr *Error) string {
+	if r != nil {
+		if rCtx, ok := kitContext.Request(r.Context()); ok && rCtx.GetRequestId() != "" {
+			return rCtx.GetRequestId()
+		}
+	}
+	if ctx, ok := httpErr.Details["ctx"].(map[string]interface{}); ok {
+		if rid, ok := ctx["_ctx.rid"].(string); ok {
+			return rid
+		}
+	}
+	return ""
+}
+
+func respondJson(w http.ResponseWriter, httpStatus int, contentType string, payload interface{}) {
+	response, _ := json.Marshal(payload)
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(httpStatus)
+	_, _ = w.Write(response)
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	kitContext "github.com/exluap/kit/context"
+	"github.com/exluap/kit/er"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_RespondError_WhenDefaultFormat(t *testing.T) {
+	c := &BaseController{}
+	w := httptest.NewRecorder()
+	c.RespondError(w, er.WithBuilder("TST-001", "not found").HttpSt(http.StatusNotFound).F(er.FF{"id": "1"}).Err())
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var rs Error
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &rs))
+	assert.Equal(t, "TST-001", rs.Code)
+	assert.Equal(t, "not found", rs.Message)
+	assert.Equal(t, "1", rs.Details["id"])
+}
+
+func Test_RespondError_WhenProblemFormat(t *testing.T) {
+	c := &BaseController{ErrorRenderer: NewErrorRenderer(&Config{ErrorFormat: ErrorFormatProblem, ProblemTypeBaseUrl: "https://errors.example.com/"})}
+	ctx := kitContext.NewRequestCtx().Rest().WithRequestId("rid-1").ToContext(context.Background())
+	r := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	c.RespondErrorWithRequest(w, r, er.WithBuilder(ErrCodeHttpUrlVar, "invalid or empty URL parameter").HttpSt(http.StatusBadRequest).F(er.FF{"var": "id", "status": "x"}).Err())
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "application/problem+json", w.Header().Get("Content-Type"))
+	var rs map[string]interface{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &rs))
+	assert.Equal(t, "https://errors.example.com/http-003", rs["type"])
+	assert.Equal(t, "invalid or empty URL parameter", rs["title"])
+	assert.Equal(t, float64(http.StatusBadRequest), rs["status"])
+	assert.Equal(t, "invalid or empty URL parameter", rs["detail"])
+	assert.Equal(t, "rid-1", rs["instance"])
+	assert.Equal(t, "id", rs["var"])
+	assert.Equal(t, ErrCodeHttpUrlVar, rs["code"])
+}
+
+func Test_RespondError_WhenProblemFormatAndNotAppErr(t *testing.T) {
+	c := &BaseController{ErrorRenderer: NewProblemRenderer("")}
+	w := httptest.NewRecorder()
+	c.RespondError(w, http.ErrBodyNotAllowed)
+
+	var rs map[string]interface{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &rs))
+	assert.Equal(t, "about:blank", rs["type"])
+	assert.Equal(t, "Internal Server Error", rs["title"])
+	assert.Nil(t, rs["instance"])
+}
+
+func Test_RespondError_WhenProblemFormatAndErrorWithContext(t *testing.T) {
+	c := &BaseController{ErrorRenderer: NewProblemRenderer("")}
+	ctx := kitContext.NewRequestCtx().Rest().WithRequestId("rid-2").WithUser("u1", "user").ToContext(context.Background())
+	w := httptest.NewRecorder()
+	c.RespondError(w, er.WithBuilder("TST-002", "forbidden").C(ctx).HttpSt(http.StatusForbidden).F(er.FF{"id": "1"}).Err())
+
+	var rs map[string]interface{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &rs))
+	assert.Nil(t, rs["ctx"])
+	assert.Equal(t, "1", rs["id"])
+	assert.Equal(t, "rid-2", rs["instance"])
+}
+
+func Test_RespondError_WhenMultiRetryable(t *testing.T) {
+	c := &BaseController{}
+	w := httptest.NewRecorder()
+	c.RespondError(w, er.NewMulti("TST-003", "batch failed").
+		Add(er.WithBuilder("TST-004", "busy").RetryAfter(1500*time.Millisecond).Err()).
+		Add(er.WithBuilder("TST-004", "busy").RetryAfter(time.Second).Err()).
+		HttpSt(http.StatusServiceUnavailable))
+
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+	var rs Error
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &rs))
+	assert.True(t, rs.Retryable)
+
+	// aggregate isn't retryable if any error isn't
+	w = httptest.NewRecorder()
+	c.RespondError(w, er.NewMulti("TST-003", "batch failed").
+		Add(er.WithBuilder("TST-004", "busy").RetryAfter(time.Second).Err(), er.New("TST-005", "failed")))
+	assert.Empty(t, w.Header().Get("Retry-After"))
+	rs = Error{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &rs))
+	assert.False(t, rs.Retryable)
+}
@@ -1,12 +1,17 @@
 package http
 
 import (
+	"crypto/tls"
 	"fmt"
 	"github.com/exluap/kit/log"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 	"github.com/rs/cors"
+	"go.uber.org/atomic"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -15,6 +20,7 @@ const (
 	WriteTimeout    = time.Second * 10
 	ReadBufferSize  = 1024
 	WriteBufferSize = 1024
+	DrainTimeout    = time.Second * 30
 )
 
 type Cors struct {
@@ -28,6 +34,43 @@ type Config struct {
 	Port  string
 	Cors  *Cors
 	Trace bool
+	// Tracing - configuration of request/response tracing enabled by Trace
+	Tracing *TraceConfig
+	// AccessLog - logs method, route, status, size, duration and request ID of every request at info level
+	AccessLog bool `config:"access-log"`
+	// Metrics - collects request count, error and duration metrics by route template, see GetCollector
+	Metrics bool `config:"metrics"`
+	// ReadTimeout - max duration of reading the entire request including body, 10s by default
+	ReadTimeout time.Duration `config:"read-timeout"`
+	// ReadHeaderTimeout - max duration of reading request headers, ReadTimeout is used if not specified
+	ReadHeaderTimeout time.Duration `config:"read-header-timeout"`
+	// WriteTimeout - max duration before timing out writes of the response, 10s by default
+	WriteTimeout time.Duration `config:"write-timeout"`
+	// IdleTimeout - max time to wait for the next request on keep-alive connection, ReadTimeout is used if not specified
+	IdleTimeout time.Duration `config:"idle-timeout"`
+	// MaxHeaderBytes - max size of request headers, 1MB by default
+	MaxHeaderBytes int `config:"max-header-bytes"`
+	// MaxBodyBytes - max size of request body, requests exceeding it are rejected with 413, not limited if not specified
+	MaxBodyBytes int64 `config:"max-body-bytes"`
+	// WsReadBufferSize - websocket read buffer size, 1024 by default
+	WsReadBufferSize int `config:"ws-read-buffer-size"`
+	// WsWriteBufferSize - websocket write buffer size, 1024 by default
+	WsWriteBufferSize int `config:"ws-write-buffer-size"`
+	// TLS - if specified, the server listens TLS connections
+	TLS *TLSConfig
+	// DisableHTTP2 - disables HTTP/2 which is otherwise negotiated over TLS
+	DisableHTTP2 bool `config:"disable-http2"`
+	// H2C - enables HTTP/2 over cleartext connections (prior knowledge or upgrade), used behind proxies terminating TLS
+	H2C bool `config:"h2c"`
+	// ErrorFormat specifies how errors are rendered: "kit" (default) or "problem" (RFC 7807)
+	ErrorFormat string `config:"error-format"`
+	// ProblemTypeBaseUrl is a base URL of problem type for "problem" error format
+	ProblemTypeBaseUrl string `config:"problem-type-base-url"`
+	// DrainTimeout - how long Shutdown waits for in-flight requests and websocket connections, 30s by default
+	DrainTimeout time.Duration `config:"drain-timeout"`
+	// ReadinessDelay - how long Shutdown waits after the server is marked not ready before draining
+	// it gives load balancers time to notice readiness change and stop routing new requests
+	ReadinessDelay time.Duration `config:"readiness-delay"`
 }
 
 // Server represents HTTP server
@@ -35,7 +78,17 @@ type Server struct {
 	Srv        *http.Server        // Srv - internal server
 	RootRouter *mux.Router         // RootRouter - root router
 	WsUpgrader *websocket.Upgrader // WsUpgrader - websocket upgrader
-	logger     log.CLoggerFunc     // logger
+	// ErrorRenderer - error renderer built according to the configuration, pass it to BaseController
+	ErrorRenderer ErrorRenderer
+	logger        log.CLoggerFunc // logger
+	cfg           *Config         // cfg - server configuration
+	ready         *atomic.Bool    // ready - readiness of the server
+	done          chan struct{}   // done - closed when listening is stopped
+	listenOnce    sync.Once       // listenOnce - guards starting listening and closing done
+	err           error           // err - error of listening
+	ws            *wsTracker      // ws - tracked websocket connections
+	tracer        *tracer         // tracer - logs requests and responses if Trace is enabled
+	metrics       *serverMetrics  // metrics - request metrics if Metrics is enabled
 }
 
 type RouteSetter interface {
@@ -63,48 +116,133 @@ func getOptions(cfg *Config) cors.Options {
 	}
 }
 
+// durationOrDefault returns d if it's specified, otherwise def
+func durationOrDefault(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
+
+// intOrDefault returns i if it's specified, otherwise def
+func intOrDefault(i, def int) int {
+	if i > 0 {
+		return i
+	}
+	return def
+}
+
+// NewHttpServer creates HTTP server
+// if TLS configuration is invalid, Listen fails immediately with the configuration error, use NewServer to get it on creation
 func NewHttpServer(cfg *Config, logger log.CLoggerFunc) *Server {
 	r := mux.NewRouter()
 
-	corsHandler := cors.New(getOptions(cfg)).Handler(r)
-
 	s := &Server{
 		Srv: &http.Server{
-			Addr:         fmt.Sprintf(":%s", cfg.Port),
-			Handler:      corsHandler,
-			WriteTimeout: WriteTimeout,
-			ReadTimeout:  ReadTimeout,
+			Addr:              fmt.Sprintf(":%s", cfg.Port),
+			WriteTimeout:      durationOrDefault(cfg.WriteTimeout, WriteTimeout),
+			ReadTimeout:       durationOrDefault(cfg.ReadTimeout, ReadTimeout),
+			ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+			IdleTimeout:       cfg.IdleTimeout,
+			MaxHeaderBytes:    cfg.MaxHeaderBytes,
 		},
 		WsUpgrader: &websocket.Upgrader{
-			ReadBufferSize:  ReadBufferSize,
-			WriteBufferSize: WriteBufferSize,
+			ReadBufferSize:  intOrDefault(cfg.WsReadBufferSize, ReadBufferSize),
+			WriteBufferSize: intOrDefault(cfg.WsWriteBufferSize, WriteBufferSize),
 			CheckOrigin: func(r *http.Request) bool {
 				return true
 			},
 		},
-		logger: logger,
+		ErrorRenderer: NewErrorRenderer(cfg),
+		logger:        logger,
+		cfg:           cfg,
+		ready:         atomic.NewBool(false),
+		done:          make(chan struct{}),
+		ws:            newWsTracker(),
+	}
+	if cfg.TLS != nil {
+		// certificates are loaded on creation, so misconfiguration is found before listening
+		s.Srv.TLSConfig, s.err = newServerTLSConfig(cfg.TLS)
+	}
+	if cfg.DisableHTTP2 {
+		// non-nil empty map disables HTTP/2 negotiation
+		s.Srv.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+	if cfg.Metrics {
+		s.metrics = newServerMetrics()
 	}
 	if cfg.Trace {
+		s.tracer = newTracer(cfg.Tracing)
 		r.Use(s.loggingMiddleware)
 	}
 	s.RootRouter = r
+	s.Srv.Handler = s.rootHandler(r)
 
 	return s
 }
 
+// NewServer creates HTTP server the same way as NewHttpServer, but returns configuration error (e.g. invalid TLS) on creation
+func NewServer(cfg *Config, logger log.CLoggerFunc) (*Server, error) {
+	s := NewHttpServer(cfg, logger)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s, nil
+}
+
+// rootHandler wraps the handler with body limit, CORS, access log and, if enabled, cleartext HTTP/2 support
+// body limit is applied to every request, not only to ones matching routes
+// access middleware goes outermost, so it observes responses of all the other middlewares and of the router
+func (s *Server) rootHandler(h http.Handler) http.Handler {
+	if s.cfg.MaxBodyBytes > 0 {
+		h = s.bodyLimitMiddleware(h)
+	}
+	h = cors.New(getOptions(s.cfg)).Handler(h)
+	if s.cfg.AccessLog || s.cfg.Metrics {
+		h = s.accessMiddleware(h)
+	}
+	if s.cfg.H2C && !s.cfg.DisableHTTP2 {
+		h = h2c.NewHandler(h, &http2.Server{IdleTimeout: s.Srv.IdleTimeout})
+	}
+	return h
+}
+
 func (s *Server) SetWsUpgrader(upgradeSetter WsUpgrader) {
 	upgradeSetter.Set(s.RootRouter, s.WsUpgrader)
 }
 
+// Listen starts listening in background and marks the server ready
+// use Done to get notified when listening is stopped
+// if the server is misconfigured, it isn't started, Done is closed and Err returns the configuration error
+// the server listens only once, subsequent calls are ignored
 func (s *Server) Listen() {
+	s.listenOnce.Do(s.listen)
+}
+
+func (s *Server) listen() {
+	l := s.logger().Pr("http").Cmp("server").Mth("listen").F(log.FF{"url": s.Srv.Addr})
+	if s.err != nil {
+		l.E(s.err).Err()
+		close(s.done)
+		return
+	}
+	s.ready.Store(true)
 	go func() {
-		l := s.logger().Pr("http").Cmp("server").Mth("listen").F(log.FF{"url": s.Srv.Addr})
+		defer close(s.done)
 		l.Inf("start listening")
 
-		// if tls parameters are specified, list tls connection
-		if err := s.Srv.ListenAndServe(); err != nil {
+		// if tls parameters are specified, listen tls connections
+		var err error
+		if s.cfg.TLS != nil {
+			err = s.Srv.ListenAndServeTLS("", "")
+		} else {
+			err = s.Srv.ListenAndServe()
+		}
+		if err != nil {
 			if err != http.ErrServerClosed {
-				l.E(ErrHttpSrvListen(err)).St().Err()
+				s.ready.Store(false)
+				s.err = ErrHttpSrvListen(err)
+				l.E(s.err).St().Err()
 			} else {
 				l.Dbg("server closed")
 			}
@@ -113,6 +251,10 @@ func (s *Server) Listen() {
 	}()
 }
 
+// Close closes the server immediately, in-flight requests are interrupted
+// use Shutdown for graceful shutdown
 func (s *Server) Close() {
+	s.ready.Store(false)
 	_ = s.Srv.Close()
+	s.ws.closeAll()
 }
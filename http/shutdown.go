@@ -0,0 +1,205 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"github.com/gorilla/websocket"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Done returns channel which is closed when listening is stopped
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns error of listening or configuration error, it's nil if the server is stopped by Close or Shutdown
+// it's set by the listening goroutine, so it's safe to call only after Done is closed
+func (s *Server) Err() error {
+	return s.err
+}
+
+// Ready checks if the server is ready to accept requests
+func (s *Server) Ready() bool {
+	return s.ready.Load()
+}
+
+// SetReady changes readiness of the server, it's marked ready by Listen and not ready by Shutdown
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+// ReadinessHandler responds 200 if the server is ready, otherwise 503
+// register it on the router to expose readiness probe
+func (s *Server) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.Ready() {
+			respondJson(w, http.StatusServiceUnavailable, "application/json", struct {
+				Status string `json:"status"`
+			}{Status: "NOT_READY"})
+			return
+		}
+		respondJson(w, http.StatusOK, "application/json", EmptyOkResponse)
+	}
+}
+
+// Shutdown stops the server gracefully
+// the server is marked not ready, after ReadinessDelay it stops accepting new connections and waits for in-flight requests
+// tracked websocket connections receive close frame and are awaited as well
+// if they don't complete within DrainTimeout or ctx is done, all connections are closed and error is returned
+func (s *Server) Shutdown(ctx context.Context) error {
+
+	l := s.logger().Pr("http").Cmp("server").Mth("shutdown")
+	s.SetReady(false)
+
+	if s.cfg.ReadinessDelay > 0 {
+		l.DbgF("not ready, waiting %v", s.cfg.ReadinessDelay)
+		select {
+		case <-time.After(s.cfg.ReadinessDelay):
+		case <-ctx.Done():
+		}
+	}
+
+	timeout := s.cfg.DrainTimeout
+	if timeout <= 0 {
+		timeout = DrainTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	s.ws.notifyAll()
+
+	err := s.Srv.Shutdown(ctx)
+	if err == nil {
+		err = s.ws.await(ctx)
+	}
+	if err != nil {
+		_ = s.Srv.Close()
+		s.ws.closeAll()
+		l.E(err).Warn("drain timeout, connections are closed")
+		return ErrHttpSrvShutdown(err)
+	}
+
+	l.Inf("ok")
+	return nil
+}
+
+// UpgradeWs upgrades connection to websocket with the server upgrader
+// connection is tracked, so it receives close frame on Shutdown which waits until the connection is closed
+func (s *Server) UpgradeWs(w http.ResponseWriter, r *http.Request, responseHeader http.Header) (*websocket.Conn, error) {
+	tw := &wsTrackingWriter{ResponseWriter: w, tracker: s.ws}
+	conn, err := s.WsUpgrader.Upgrade(tw, r, responseHeader)
+	if err != nil {
+		return nil, ErrHttpWsUpgrade(err, r.Context())
+	}
+	s.ws.add(tw.conn, conn)
+	return conn, nil
+}
+
+// wsTracker keeps open websocket connections
+type wsTracker struct {
+	sync.Mutex
+	conns map[*wsNetConn]*websocket.Conn
+	empty chan struct{}
+}
+
+func newWsTracker() *wsTracker {
+	return &wsTracker{conns: make(map[*wsNetConn]*websocket.Conn)}
+}
+
+func (t *wsTracker) add(nc *wsNetConn, c *websocket.Conn) {
+	t.Lock()
+	defer t.Unlock()
+	// connection might be closed already
+	if !nc.closed {
+		t.conns[nc] = c
+	}
+}
+
+func (t *wsTracker) remove(nc *wsNetConn) {
+	t.Lock()
+	defer t.Unlock()
+	nc.closed = true
+	delete(t.conns, nc)
+	if len(t.conns) == 0 && t.empty != nil {
+		close(t.empty)
+		t.empty = nil
+	}
+}
+
+// notifyAll sends close frame to all connections, clients are expected to close connections
+func (t *wsTracker) notifyAll() {
+	t.Lock()
+	defer t.Unlock()
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutdown")
+	for _, c := range t.conns {
+		_ = c.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	}
+}
+
+// await waits until all connections are closed
+func (t *wsTracker) await(ctx context.Context) error {
+	t.Lock()
+	if len(t.conns) == 0 {
+		t.Unlock()
+		return nil
+	}
+	if t.empty == nil {
+		t.empty = make(chan struct{})
+	}
+	empty := t.empty
+	t.Unlock()
+	select {
+	case <-empty:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *wsTracker) closeAll() {
+	t.Lock()
+	conns := make([]*websocket.Conn, 0, len(t.conns))
+	for _, c := range t.conns {
+		conns = append(conns, c)
+	}
+	t.Unlock()
+	for _, c := range conns {
+		_ = c.Close()
+	}
+}
+
+// wsTrackingWriter intercepts hijacking to get notified when the connection is closed
+type wsTrackingWriter struct {
+	http.ResponseWriter
+	tracker *wsTracker
+	conn    *wsNetConn
+}
+
+func (w *wsTrackingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	c, rw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	w.conn = &wsNetConn{Conn: c, tracker: w.tracker}
+	return w.conn, rw, nil
+}
+
+// wsNetConn removes the connection from tracker when it's closed
+type wsNetConn struct {
+	net.Conn
+	tracker *wsTracker
+	once    sync.Once
+	closed  bool
+}
+
+func (c *wsNetConn) Close() error {
+	c.once.Do(func() { c.tracker.remove(c) })
+	return c.Conn.Close()
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"github.com/exluap/kit/er"
+	"github.com/exluap/kit/log"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func testLogger() log.CLoggerFunc {
+	return func() log.CLogger {
+		return log.L(log.Init(&log.Config{Level: log.TraceLevel}))
+	}
+}
+
+// startTestServer starts kit server on a free port and waits until it accepts connections
+func startTestServer(t *testing.T, cfg *Config, setup func(s *Server)) (*Server, string) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	_, port, _ := net.SplitHostPort(lis.Addr().String())
+	_ = lis.Close()
+
+	cfg.Port = port
+	s := NewHttpServer(cfg, testLogger())
+	setup(s)
+	s.Listen()
+	assert.Eventually(t, func() bool {
+		c, err := net.Dial("tcp", "127.0.0.1:"+port)
+		if err == nil {
+			_ = c.Close()
+		}
+		return err == nil
+	}, time.Second*5, time.Millisecond*10)
+	return s, "127.0.0.1:" + port
+}
+
+func Test_Shutdown_DrainsRequests(t *testing.T) {
+	started := make(chan struct{})
+	s, addr := startTestServer(t, &Config{}, func(s *Server) {
+		s.RootRouter.HandleFunc("/ready", s.ReadinessHandler())
+		s.RootRouter.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(time.Millisecond * 200)
+			(&BaseController{}).RespondOK(w, EmptyOkResponse)
+		})
+	})
+
+	rs, err := http.Get(fmt.Sprintf("http://%s/ready", addr))
+	assert.Nil(t, err)
+	_ = rs.Body.Close()
+	assert.Equal(t, http.StatusOK, rs.StatusCode)
+
+	result := make(chan int)
+	go func() {
+		rs, err := http.Get(fmt.Sprintf("http://%s/slow", addr))
+		assert.Nil(t, err)
+		_ = rs.Body.Close()
+		result <- rs.StatusCode
+	}()
+	<-started
+
+	assert.Nil(t, s.Shutdown(context.Background()))
+	assert.False(t, s.Ready())
+	assert.Equal(t, http.StatusOK, <-result)
+
+	<-s.Done()
+	assert.Nil(t, s.Err())
+
+	// repeated listening is ignored
+	assert.NotPanics(t, s.Listen)
+	assert.False(t, s.Ready())
+}
+
+func Test_Shutdown_ClosesWebsockets(t *testing.T) {
+	s, addr := startTestServer(t, &Config{}, func(s *Server) {
+		s.RootRouter.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+			conn, err := s.UpgradeWs(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		})
+	})
+
+	conn, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("ws://%s/ws", addr), nil)
+	assert.Nil(t, err)
+	closed := make(chan error)
+	go func() {
+		_, _, err := conn.ReadMessage()
+		// default close handler responds with close frame
+		closed <- err
+		_ = conn.Close()
+	}()
+
+	assert.Nil(t, s.Shutdown(context.Background()))
+	err = <-closed
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+}
+
+func Test_Shutdown_WhenDrainTimeout(t *testing.T) {
+	s, addr := startTestServer(t, &Config{DrainTimeout: time.Millisecond * 100}, func(s *Server) {
+		s.RootRouter.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+			// connection is never closed by the handler
+			_, _ = s.UpgradeWs(w, r, nil)
+		})
+	})
+
+	// client doesn't read, so close frame isn't answered
+	conn, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("ws://%s/ws", addr), nil)
+	assert.Nil(t, err)
+	defer conn.Close()
+	assert.Eventually(t, func() bool {
+		s.ws.Lock()
+		defer s.ws.Unlock()
+		return len(s.ws.conns) == 1
+	}, time.Second, time.Millisecond*10)
+
+	err = s.Shutdown(context.Background())
+	assert.True(t, er.HasCode(err, ErrCodeHttpSrvShutdown))
+	s.ws.Lock()
+	assert.Empty(t, s.ws.conns)
+	s.ws.Unlock()
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+)
+
+// TLSConfig is TLS configuration of HTTP server
+type TLSConfig struct {
+	// CertFile - path to PEM certificate
+	CertFile string `config:"cert-file"`
+	// KeyFile - path to PEM private key of the certificate
+	KeyFile string `config:"key-file"`
+	// ClientCAFile - path to PEM CA bundle to verify client certificates
+	ClientCAFile string `config:"client-ca-file"`
+	// ClientAuth - requires and verifies client certificates (mTLS), if not set, client certificates are verified only if presented
+	ClientAuth bool `config:"client-auth"`
+}
+
+// newServerTLSConfig builds TLS configuration of the server
+func newServerTLSConfig(cfg *TLSConfig) (*tls.Config, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, ErrHttpTLS(errors.New("cert and key files must be specified"))
+	}
+	if cfg.ClientAuth && cfg.ClientCAFile == "" {
+		return nil, ErrHttpTLS(errors.New("client CA file must be specified to verify client certificates"))
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, ErrHttpTLS(err)
+	}
+	res := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if cfg.ClientCAFile != "" {
+		data, err := ioutil.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, ErrHttpTLS(err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, ErrHttpTLS(errors.New("no certificates found in client CA file"))
+		}
+		res.ClientCAs = pool
+		res.ClientAuth = tls.VerifyClientCertIfGiven
+		if cfg.ClientAuth {
+			res.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return res, nil
+}
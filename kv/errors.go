@@ -7,5 +7,11 @@ const (
 )
 
 var (
-	ErrEtcdOpen = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeEtcdOpen, "").Err() }
+	ErrEtcdOpen = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeEtcdOpen, "").Retryable().Err() }
 )
+
+func init() {
+	er.Register(
+		er.CatalogEntry{Code: ErrCodeEtcdOpen, Message: "etcd open failed", Retryable: true},
+	)
+}
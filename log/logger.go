@@ -7,7 +7,6 @@ import (
 	"github.com/exluap/kit/er"
 	"github.com/sirupsen/logrus"
 	"os"
-	"runtime"
 )
 
 const (
@@ -103,7 +102,7 @@ type CLogger interface {
 	F(fields FF) CLogger
 	// E - adds error to log
 	E(err error) CLogger
-	// St - adds stack to log (if err is already set)
+	// St - adds stack to log (if err is already set) as an array of frames
 	St() CLogger
 	// Cmp - adds component
 	Cmp(c string) CLogger
@@ -119,6 +118,9 @@ type CLogger interface {
 	InfF(format string, args ...interface{}) CLogger
 	Err(args ...interface{}) CLogger
 	ErrF(format string, args ...interface{}) CLogger
+	// ErrSev logs with level chosen by severity of error attached with E
+	// business errors are logged with info level, warning errors with warning level, others with error level
+	ErrSev(args ...interface{}) CLogger
 	Dbg(args ...interface{}) CLogger
 	DbgF(format string, args ...interface{}) CLogger
 	Trc(args ...interface{}) CLogger
@@ -178,6 +180,18 @@ func (cl *clogger) C(ctx context.Context) CLogger {
 		if sid := r.GetSessionId(); sid != "" {
 			ff["ctx.sid"] = sid
 		}
+		if tid := r.GetTraceId(); tid != "" {
+			ff["ctx.tid"] = tid
+		}
+		if spid := r.GetSpanId(); spid != "" {
+			ff["ctx.spid"] = spid
+		}
+		if pspid := r.GetParentSpanId(); pspid != "" {
+			ff["ctx.pspid"] = pspid
+		}
+		if bg := r.GetBaggage(); len(bg) > 0 {
+			ff["ctx.bg"] = bg
+		}
 		cl.F(ff)
 	}
 	return cl
@@ -198,6 +212,7 @@ func (cl *clogger) E(err error) CLogger {
 		// put code / message as fields
 		cl.lre = cl.lre.WithField("err-code", appErr.Code())
 		cl.lre = cl.lre.WithField("error", appErr.Message())
+		cl.lre = cl.lre.WithField("err-sev", appErr.Severity())
 
 		// pass fields from err to log
 		for k, v := range appErr.Fields() {
@@ -214,11 +229,11 @@ func (cl *clogger) St() CLogger {
 	if cl.err != nil {
 		// if err is AppErr take stack from error itself, otherwise build stack right here
 		if appErr, ok := er.Is(cl.err); ok {
-			cl.lre = cl.lre.WithField("err-stack", appErr.WithStack())
+			if frames := appErr.Frames(); len(frames) > 0 {
+				cl.lre = cl.lre.WithField("err-stack", frames)
+			}
 		} else {
-			buf := make([]byte, 1<<16)
-			runtime.Stack(buf, false)
-			cl.lre = cl.lre.WithField("err-stack", string(buf))
+			cl.lre = cl.lre.WithField("err-stack", er.Stack(1))
 		}
 	}
 	return cl
@@ -259,6 +274,18 @@ func (cl *clogger) ErrF(format string, args ...interface{}) CLogger {
 	return cl
 }
 
+func (cl *clogger) ErrSev(args ...interface{}) CLogger {
+	switch er.SeverityOf(cl.err) {
+	case er.SeverityBusiness:
+		cl.lre.Infoln(args...)
+	case er.SeverityWarning:
+		cl.lre.Warningln(args...)
+	default:
+		cl.lre.Errorln(args...)
+	}
+	return cl
+}
+
 func (cl *clogger) Inf(args ...interface{}) CLogger {
 	cl.lre.Infoln(args...)
 	return cl
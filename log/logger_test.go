@@ -106,3 +106,10 @@ func Test_Clogger_WithAppErrAndAppContext(t *testing.T) {
 	l := L(logger).E(e)
 	l.Err("my bad")
 }
+
+func Test_Clogger_WithAppErrSeverity(t *testing.T) {
+	logger := Init(&Config{Level: TraceLevel})
+	L(logger).E(er.WithBuilder("ERR-123", "not found").HttpSt(404).Err()).ErrSev("my bad")
+	L(logger).E(er.WithBuilder("ERR-123", "expected").Severity(er.SeverityBusiness).Err()).ErrSev("my bad")
+	L(logger).E(er.New("ERR-123", "%s happened", "shit")).ErrSev("my bad")
+}
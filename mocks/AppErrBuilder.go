@@ -7,6 +7,8 @@ import (
 
 	er "github.com/exluap/kit/er"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // AppErrBuilder is an autogenerated mock type for the AppErrBuilder type
@@ -91,3 +93,51 @@ func (_m *AppErrBuilder) HttpSt(status uint32) er.AppErrBuilder {
 
 	return r0
 }
+
+// RetryAfter provides a mock function with given fields: d
+func (_m *AppErrBuilder) RetryAfter(d time.Duration) er.AppErrBuilder {
+	ret := _m.Called(d)
+
+	var r0 er.AppErrBuilder
+	if rf, ok := ret.Get(0).(func(time.Duration) er.AppErrBuilder); ok {
+		r0 = rf(d)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(er.AppErrBuilder)
+		}
+	}
+
+	return r0
+}
+
+// Retryable provides a mock function with given fields:
+func (_m *AppErrBuilder) Retryable() er.AppErrBuilder {
+	ret := _m.Called()
+
+	var r0 er.AppErrBuilder
+	if rf, ok := ret.Get(0).(func() er.AppErrBuilder); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(er.AppErrBuilder)
+		}
+	}
+
+	return r0
+}
+
+// Severity provides a mock function with given fields: s
+func (_m *AppErrBuilder) Severity(s er.Severity) er.AppErrBuilder {
+	ret := _m.Called(s)
+
+	var r0 er.AppErrBuilder
+	if rf, ok := ret.Get(0).(func(er.Severity) er.AppErrBuilder); ok {
+		r0 = rf(s)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(er.AppErrBuilder)
+		}
+	}
+
+	return r0
+}
@@ -205,6 +205,24 @@ func (_m *CLogger) FatalF(format string, args ...interface{}) log.CLogger {
 	return r0
 }
 
+// ErrSev provides a mock function with given fields: args
+func (_m *CLogger) ErrSev(args ...interface{}) log.CLogger {
+	var _ca []interface{}
+	_ca = append(_ca, args...)
+	ret := _m.Called(_ca...)
+
+	var r0 log.CLogger
+	if rf, ok := ret.Get(0).(func(...interface{}) log.CLogger); ok {
+		r0 = rf(args...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(log.CLogger)
+		}
+	}
+
+	return r0
+}
+
 // Inf provides a mock function with given fields: args
 func (_m *CLogger) Inf(args ...interface{}) log.CLogger {
 	var _ca []interface{}
@@ -25,3 +25,13 @@ var (
 		return er.WrapWithBuilder(cause, ErrCodePrometheusRegisterAppMetrics, "").Err()
 	}
 )
+
+func init() {
+	er.Register(
+		er.CatalogEntry{Code: ErrCodePrometheusRegisterGoMetrics, Message: "go metrics registration failed"},
+		er.CatalogEntry{Code: ErrCodePrometheusRegisterProcessMetrics, Message: "process metrics registration failed"},
+		er.CatalogEntry{Code: ErrCodePrometheusHttpServer, Message: "prometheus http server failed"},
+		er.CatalogEntry{Code: ErrCodePrometheusInvalidPort, Message: "invalid port"},
+		er.CatalogEntry{Code: ErrCodePrometheusRegisterAppMetrics, Message: "app metrics registration failed"},
+	)
+}
@@ -40,6 +40,10 @@ func (s *prometheusMetricsSrv) Init(config *Config, metricProviders ...MetricsPr
 	for _, pr := range metricProviders {
 		for _, m := range pr.GetCollector()() {
 			if err := s.registerer.Register(m); err != nil {
+				// collectors shared by several providers are registered once
+				if are, ok := err.(prometheus.AlreadyRegisteredError); ok && are.ExistingCollector == m {
+					continue
+				}
 				return ErrPrometheusRegisterAppMetrics(err)
 			}
 		}
@@ -11,3 +11,10 @@ var (
 	ErrQueueMsgUnmarshal        = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeQueueMsgUnmarshal, "").Err() }
 	ErrQueueMsgUnmarshalPayload = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeQueueMsgUnmarshalPayload, "").Err() }
 )
+
+func init() {
+	er.Register(
+		er.CatalogEntry{Code: ErrCodeQueueMsgUnmarshal, Message: "message unmarshal failed"},
+		er.CatalogEntry{Code: ErrCodeQueueMsgUnmarshalPayload, Message: "message payload unmarshal failed"},
+	)
+}
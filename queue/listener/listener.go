@@ -109,7 +109,7 @@ func (q *queueListener) ListenAsync() {
 							go func() {
 								l := q.logger().Pr("queue").Cmp("listener").F(log.FF{"topic": tp}).TrcF("%s", string(m))
 								if err := h(m); err != nil {
-									l.E(err).St().Err()
+									l.E(err).St().ErrSev()
 								}
 							}()
 						}
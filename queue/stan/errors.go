@@ -18,10 +18,27 @@ var (
 	ErrStanQtNotSupported = func(qt int) error {
 		return er.WithBuilder(ErrCodeStanQtNotSupported, "queue type not supported").F(er.FF{"qt": qt}).Err()
 	}
-	ErrStanConnect              = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeStanConnect, "").Err() }
-	ErrStanClose                = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeStanClose, "").Err() }
-	ErrStanPublishAtLeastOnce   = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeStanPublishAtLeastOnce, "").Err() }
-	ErrStanPublishAtMostOnce    = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeStanPublishAtMostOnce, "").Err() }
+	ErrStanConnect            = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeStanConnect, "").Err() }
+	ErrStanClose              = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeStanClose, "").Err() }
+	ErrStanPublishAtLeastOnce = func(cause error) error {
+		return er.WrapWithBuilder(cause, ErrCodeStanPublishAtLeastOnce, "").Retryable().Err()
+	}
+	ErrStanPublishAtMostOnce = func(cause error) error {
+		return er.WrapWithBuilder(cause, ErrCodeStanPublishAtMostOnce, "").Retryable().Err()
+	}
 	ErrStanSubscribeAtLeastOnce = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeStanSubscribeAtLeastOnce, "").Err() }
 	ErrStanSubscribeAtMostOnce  = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeStanSubscribeAtMostOnce, "").Err() }
 )
+
+func init() {
+	er.Register(
+		er.CatalogEntry{Code: ErrCodeStanNoOpenConn, Message: "no open connections"},
+		er.CatalogEntry{Code: ErrCodeStanQtNotSupported, Message: "queue type not supported"},
+		er.CatalogEntry{Code: ErrCodeStanConnect, Message: "stan connect failed"},
+		er.CatalogEntry{Code: ErrCodeStanClose, Message: "stan close failed"},
+		er.CatalogEntry{Code: ErrCodeStanPublishAtLeastOnce, Message: "publish at-least-once failed", Retryable: true},
+		er.CatalogEntry{Code: ErrCodeStanPublishAtMostOnce, Message: "publish at-most-once failed", Retryable: true},
+		er.CatalogEntry{Code: ErrCodeStanSubscribeAtLeastOnce, Message: "subscribe at-least-once failed"},
+		er.CatalogEntry{Code: ErrCodeStanSubscribeAtMostOnce, Message: "subscribe at-most-once failed"},
+	)
+}
@@ -61,7 +61,7 @@ func (s *stanImpl) Publish(ctx context.Context, qt queue.QueueType, topic string
 	l := s.l().Mth("publish").F(log.FF{"topic": topic, "type": qt.String()})
 
 	if msg.Ctx == nil {
-		msg.Ctx = kitContext.NewRequestCtx().Queue().WithNewRequestId()
+		msg.Ctx = kitContext.NewRequestCtx().Queue().WithNewRequestId().WithNewTrace()
 	}
 	l.C(msg.Ctx.ToContext(context.Background()))
 
@@ -63,3 +63,23 @@ var (
 		return er.WithBuilder(ErrCodeEsInvalidModelType, "model must be pointer of struct").Err()
 	}
 )
+
+func init() {
+	er.Register(
+		er.CatalogEntry{Code: ErrCodeEsNewClient, Message: "es client creation failed"},
+		er.CatalogEntry{Code: ErrCodeEsIdxExists, Message: "index exists check failed"},
+		er.CatalogEntry{Code: ErrCodeEsIdx, Message: "indexation failed"},
+		er.CatalogEntry{Code: ErrCodeEsIdxAsync, Message: "async indexation failed"},
+		er.CatalogEntry{Code: ErrCodeEsIdxCreate, Message: "index creation failed"},
+		er.CatalogEntry{Code: ErrCodeEsBulkIdxAsync, Message: "bulk indexation failed"},
+		er.CatalogEntry{Code: ErrCodeEsExists, Message: "document exists check failed"},
+		er.CatalogEntry{Code: ErrCodeEsAwaitExistsTimeout, Message: "await timeout"},
+		er.CatalogEntry{Code: ErrCodeEsInvalidModel, Message: "invalid model, check tags"},
+		er.CatalogEntry{Code: ErrCodeEsInvalidModelType, Message: "model must be pointer of struct"},
+		er.CatalogEntry{Code: ErrCodeEsGetMapping, Message: "get mapping failed"},
+		er.CatalogEntry{Code: ErrCodeEsNoMappingFound, Message: "no mapping found"},
+		er.CatalogEntry{Code: ErrCodeEsMappingSchemaNotExpected, Message: "mapping schema not expected"},
+		er.CatalogEntry{Code: ErrCodeEsMappingExistentFieldsModified, Message: "ES doesn't allow changing mapping for existent fields."},
+		er.CatalogEntry{Code: ErrCodeEsPutMapping, Message: "put mapping failed"},
+	)
+}
@@ -9,6 +9,7 @@ const (
 	ErrCodeSvcClusterInitOddSize = "SVC-004"
 	ErrCodeRaftInit              = "SVC-005"
 	ErrCodeRaftStart             = "SVC-006"
+	ErrCodeServiceInit           = "SVC-007"
 )
 
 var (
@@ -18,6 +19,21 @@ var (
 	ErrSvcClusterInitOddSize = func() error {
 		return er.WithBuilder(ErrCodeSvcClusterInitOddSize, "cannot start cluster with odd size").Err()
 	}
-	ErrRaftInit  = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeRaftInit, "").Err() }
-	ErrRaftStart = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeRaftStart, "").Err() }
+	ErrRaftInit    = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeRaftInit, "").Err() }
+	ErrRaftStart   = func(cause error) error { return er.WrapWithBuilder(cause, ErrCodeRaftStart, "").Err() }
+	ErrServiceInit = func(cause error, svc string) error {
+		return er.WrapWithBuilder(cause, ErrCodeServiceInit, "service init failed").F(er.FF{"svc": svc}).Err()
+	}
 )
+
+func init() {
+	er.Register(
+		er.CatalogEntry{Code: ErrCodeRaftOddSize, Message: "cannot start cluster with odd size"},
+		er.CatalogEntry{Code: ErrCodeNatsRpc, Message: "nats rpc failed"},
+		er.CatalogEntry{Code: ErrCodeStart, Message: "raft node start failed"},
+		er.CatalogEntry{Code: ErrCodeSvcClusterInitOddSize, Message: "cannot start cluster with odd size"},
+		er.CatalogEntry{Code: ErrCodeRaftInit, Message: "raft init failed"},
+		er.CatalogEntry{Code: ErrCodeRaftStart, Message: "raft start failed"},
+		er.CatalogEntry{Code: ErrCodeServiceInit, Message: "service init failed"},
+	)
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+)
+
+// HealthReporter receives serving status of services
+// it's implemented by the kit gRPC health server, so the status is exposed via grpc.health.v1
+type HealthReporter interface {
+	// SetServing sets serving status of the service or sub-service
+	// it fails if the reporter is shut down
+	SetServing(service string, serving bool) error
+}
+
+// HealthAware is implemented by services which report status of their sub-services (e.g. consumers, storages)
+// a sub-service name should be prefixed with the service code, use SubServiceName
+type HealthAware interface {
+	SetHealthReporter(r HealthReporter)
+}
+
+// SubServiceName builds name of the sub-service reported to health service
+func SubServiceName(svcCode, sub string) string {
+	return svcCode + "." + sub
+}
+
+// InitWithHealth initializes the services and reports their status
+// all services are NOT_SERVING before initialization, so readiness isn't reported until all of them are initialized
+func InitWithHealth(ctx context.Context, r HealthReporter, services ...Service) error {
+	for _, s := range services {
+		if err := r.SetServing(s.GetCode(), false); err != nil {
+			return err
+		}
+		if ha, ok := s.(HealthAware); ok {
+			ha.SetHealthReporter(r)
+		}
+	}
+	for _, s := range services {
+		if err := s.Init(ctx); err != nil {
+			return ErrServiceInit(err, s.GetCode())
+		}
+		if err := r.SetServing(s.GetCode(), true); err != nil {
+			return err
+		}
+	}
+	return nil
+}